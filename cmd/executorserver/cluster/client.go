@@ -0,0 +1,179 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/goccy/go-json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// client 为访问协调节点或者其他 executorserver 的 http 接口
+type client struct {
+	base string
+	key  string // 服务器启用认证时使用的 api key
+	http *http.Client
+}
+
+func newClient(base, key string, c *http.Client) *client {
+	if c == nil {
+		c = http.DefaultClient
+	}
+	return &client{base: strings.TrimSuffix(base, "/"), key: key, http: c}
+}
+
+// statusError 表示服务器返回了非 2xx 的状态码
+type statusError struct {
+	code int
+	msg  string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("server returned %d: %s", e.code, e.msg)
+}
+
+func isNotFound(err error) bool {
+	se, ok := err.(*statusError)
+	return ok && se.code == http.StatusNotFound
+}
+
+// do 发送请求，body 非空时以 json 编码，out 非空并且返回内容时以 json 解码
+func (c *client) do(ctx context.Context, method, path string, body, out any) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, r)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	}
+	return c.send(req, out)
+}
+
+func (c *client) send(req *http.Request, out any) error {
+	resp, err := c.doRequest(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &statusError{code: resp.StatusCode, msg: strings.TrimSpace(string(b))}
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.Unmarshal(b, out)
+}
+
+func (c *client) doRequest(req *http.Request) (*http.Response, error) {
+	if c.key != "" {
+		req.Header.Set("Authorization", "Bearer "+c.key)
+	}
+	return c.http.Do(req)
+}
+
+func (c *client) register(ctx context.Context, reg Registration) (RegisterResponse, error) {
+	var rt RegisterResponse
+	err := c.do(ctx, http.MethodPost, "/cluster/nodes", reg, &rt)
+	return rt, err
+}
+
+func (c *client) deregister(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/cluster/nodes/"+id, nil, nil)
+}
+
+func (c *client) heartbeat(ctx context.Context, id string, req HeartbeatRequest) (HeartbeatResponse, error) {
+	var rt HeartbeatResponse
+	err := c.do(ctx, http.MethodPost, "/cluster/nodes/"+id+"/heartbeat", req, &rt)
+	return rt, err
+}
+
+// poll 长轮询下一个请求，没有请求时返回 nil
+func (c *client) poll(ctx context.Context, id string, wait time.Duration) (*Task, error) {
+	var t Task
+	q := url.Values{"wait": {wait.String()}}
+	if err := c.do(ctx, http.MethodGet, "/cluster/nodes/"+id+"/task?"+q.Encode(), nil, &t); err != nil {
+		return nil, err
+	}
+	if t.ID == "" {
+		return nil, nil
+	}
+	return &t, nil
+}
+
+func (c *client) report(ctx context.Context, id, taskID string, res any) error {
+	return c.do(ctx, http.MethodPost, "/cluster/nodes/"+id+"/tasks/"+taskID, res, nil)
+}
+
+// download 下载协调节点文件存储中的文件
+func (c *client) download(ctx context.Context, fileID string, w io.Writer) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.base+"/file/"+fileID, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.doRequest(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", &statusError{code: resp.StatusCode, msg: "download " + fileID}
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return "", err
+	}
+	name := fileID
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		name = params["filename"]
+	}
+	return name, nil
+}
+
+// remove 删除远端文件存储中的文件
+func (c *client) remove(ctx context.Context, fileID string) error {
+	return c.do(ctx, http.MethodDelete, "/file/"+fileID, nil, nil)
+}
+
+// upload 上传文件到协调节点的文件存储，返回文件 id
+func (c *client) upload(ctx context.Context, name string, r io.Reader) (string, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fw, err := mw.CreateFormFile("file", name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(fw, r); err != nil {
+		return "", err
+	}
+	if err := mw.Close(); err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.base+"/file", &buf)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	var id string
+	err = c.send(req, &id)
+	return id, err
+}
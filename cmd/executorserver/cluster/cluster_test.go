@@ -0,0 +1,213 @@
+package cluster_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/cluster"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/model"
+	restexecutor "github.com/lxhcaicai/loj-judge/cmd/executorserver/rest_executor"
+	"github.com/lxhcaicai/loj-judge/envexec"
+	"github.com/lxhcaicai/loj-judge/worker"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const nodeTimeout = 300 * time.Millisecond
+
+// stubWorker 只接收协调节点设置的并行数，其余方法不会被调用
+type stubWorker struct {
+	worker.Worker
+}
+
+func (stubWorker) SetParallelism(int) {}
+
+func newTestServer(t *testing.T) (*cluster.Coordinator, *httptest.Server) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	coord := cluster.NewCoordinator(cluster.CoordinatorConfig{
+		NodeTimeout: nodeTimeout,
+		Logger:      zap.NewNop(),
+	})
+	coord.Start(stubWorker{})
+	t.Cleanup(coord.Shutdown)
+
+	r := gin.New()
+	restexecutor.NewCluster(coord).Register(r)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return coord, srv
+}
+
+func call(t *testing.T, srv *httptest.Server, method, path string, body, out any) int {
+	t.Helper()
+	var b []byte
+	if body != nil {
+		var err error
+		if b, err = json.Marshal(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req, err := http.NewRequest(method, srv.URL+path, bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil && resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func register(t *testing.T, srv *httptest.Server, name string) string {
+	t.Helper()
+	var rt cluster.RegisterResponse
+	if code := call(t, srv, http.MethodPost, "/cluster/nodes", cluster.Registration{Name: name, Capacity: 1}, &rt); code != http.StatusOK {
+		t.Fatalf("register %s: status %d", name, code)
+	}
+	if rt.ID == "" || rt.Heartbeat != nodeTimeout/3 {
+		t.Fatalf("unexpected registration: %+v", rt)
+	}
+	return rt.ID
+}
+
+func poll(t *testing.T, srv *httptest.Server, id string) cluster.Task {
+	t.Helper()
+	var task cluster.Task
+	if code := call(t, srv, http.MethodGet, "/cluster/nodes/"+id+"/task?wait=1s", nil, &task); code != http.StatusOK {
+		t.Fatalf("poll %s: status %d", id, code)
+	}
+	return task
+}
+
+func execute(ctx context.Context, coord *cluster.Coordinator) <-chan worker.Response {
+	ch := make(chan worker.Response, 1)
+	go func() {
+		ch <- coord.Execute(ctx, &worker.Request{
+			RequestID: "req",
+			Cmd:       []worker.Cmd{{Args: []string{"true"}}},
+		})
+	}()
+	return ch
+}
+
+func TestRequeueAfterNodeTimeout(t *testing.T) {
+	coord, srv := newTestServer(t)
+	rtCh := execute(context.Background(), coord)
+
+	node1 := register(t, srv, "node1")
+	task := poll(t, srv, node1)
+	if task.Request == nil || task.Request.RequestID != "req" {
+		t.Fatalf("unexpected task: %+v", task)
+	}
+
+	// node1 不再发送心跳，超时后被移除
+	deadline := time.Now().Add(5 * time.Second)
+	for len(coord.Nodes()) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("node not removed after timeout")
+		}
+		time.Sleep(nodeTimeout / 10)
+	}
+	if code := call(t, srv, http.MethodPost, "/cluster/nodes/"+node1+"/heartbeat", nil, nil); code != http.StatusNotFound {
+		t.Fatalf("expected heartbeat of removed node 404, got %d", code)
+	}
+
+	// 执行中的请求重新排队并分发给其他节点
+	node2 := register(t, srv, "node2")
+	if got := poll(t, srv, node2); got.ID != task.ID {
+		t.Fatalf("expected task %s requeued, got %s", task.ID, got.ID)
+	}
+
+	res := model.Response{RequestID: "req", Results: []model.Result{{Status: model.Status(envexec.StatusAccepted)}}}
+	if code := call(t, srv, http.MethodPost, "/cluster/nodes/"+node1+"/tasks/"+task.ID, res, nil); code != http.StatusNotFound {
+		t.Fatalf("expected report from removed node 404, got %d", code)
+	}
+	if code := call(t, srv, http.MethodPost, "/cluster/nodes/"+node2+"/tasks/"+task.ID, res, nil); code != http.StatusOK {
+		t.Fatalf("expected report accepted, got %d", code)
+	}
+
+	select {
+	case rt := <-rtCh:
+		if rt.Error != nil || len(rt.Results) != 1 || rt.Results[0].Status != envexec.StatusAccepted {
+			t.Fatalf("unexpected response: %+v", rt)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("execute not returned after report")
+	}
+}
+
+func TestCancelRunningTask(t *testing.T) {
+	coord, srv := newTestServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	rtCh := execute(ctx, coord)
+
+	node := register(t, srv, "node")
+	task := poll(t, srv, node)
+	cancel()
+	if rt := <-rtCh; rt.Error == nil {
+		t.Fatal("expected cancelled execute to return an error")
+	}
+
+	// 取消的请求通过下一次心跳通知节点，并且只通知一次
+	var hb cluster.HeartbeatResponse
+	if code := call(t, srv, http.MethodPost, "/cluster/nodes/"+node+"/heartbeat", nil, &hb); code != http.StatusOK {
+		t.Fatalf("heartbeat: status %d", code)
+	}
+	if len(hb.Cancelled) != 1 || hb.Cancelled[0] != task.ID {
+		t.Fatalf("expected task %s cancelled, got %v", task.ID, hb.Cancelled)
+	}
+	hb = cluster.HeartbeatResponse{}
+	call(t, srv, http.MethodPost, "/cluster/nodes/"+node+"/heartbeat", nil, &hb)
+	if len(hb.Cancelled) != 0 {
+		t.Fatalf("expected no cancelled task, got %v", hb.Cancelled)
+	}
+
+	res := model.Response{RequestID: "req", Results: []model.Result{{Status: model.Status(envexec.StatusAccepted)}}}
+	if code := call(t, srv, http.MethodPost, "/cluster/nodes/"+node+"/tasks/"+task.ID, res, nil); code != http.StatusNotFound {
+		t.Fatalf("expected report of cancelled task 404, got %d", code)
+	}
+}
+
+func TestRequeueUndeliveredTask(t *testing.T) {
+	coord, srv := newTestServer(t)
+	execute(context.Background(), coord)
+
+	node1 := register(t, srv, "node1")
+	node2 := register(t, srv, "node2")
+	task := poll(t, srv, node1)
+
+	// 分配后的节点超时时间内，心跳中没有该请求不会重新排队 (长轮询的响应可能仍在传输中)
+	heartbeat := func(id string) {
+		if code := call(t, srv, http.MethodPost, "/cluster/nodes/"+id+"/heartbeat", cluster.HeartbeatRequest{Running: []string{}}, nil); code != http.StatusOK {
+			t.Fatalf("heartbeat %s: status %d", id, code)
+		}
+	}
+	heartbeat(node1)
+	for _, n := range coord.Nodes() {
+		if n.ID == node1 && n.Running != 1 {
+			t.Fatalf("expected task not requeued before node timeout, got %+v", n)
+		}
+	}
+
+	// node1 没有收到长轮询的响应，超时后请求重新分发给其他节点
+	for deadline := time.Now().Add(nodeTimeout + nodeTimeout/10); time.Now().Before(deadline); {
+		time.Sleep(nodeTimeout / 5)
+		heartbeat(node1)
+		heartbeat(node2)
+	}
+	if got := poll(t, srv, node2); got.ID != task.ID {
+		t.Fatalf("expected task %s requeued, got %s", task.ID, got.ID)
+	}
+}
@@ -0,0 +1,403 @@
+package cluster
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/model"
+	"github.com/lxhcaicai/loj-judge/worker"
+	"go.uber.org/zap"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrUnknownNode 表示节点未注册或者已经因为没有心跳被移除，节点需要重新注册
+	ErrUnknownNode = errors.New("unknown node")
+	// ErrUnknownTask 表示请求不在该节点上执行，例如已经被重新分发或者被取消
+	ErrUnknownTask = errors.New("unknown task")
+)
+
+// Registration 定义节点注册时提供的信息
+type Registration struct {
+	Name     string `json:"name"`
+	Capacity int    `json:"capacity"` // 同时执行的请求数量
+}
+
+// RegisterResponse 定义注册成功后返回给节点的信息
+type RegisterResponse struct {
+	ID        string        `json:"id"`
+	Heartbeat time.Duration `json:"heartbeat"` // 心跳间隔 (纳秒)
+}
+
+// HeartbeatRequest 定义节点心跳时报告的状态
+type HeartbeatRequest struct {
+	// Running 为节点执行中的请求，为 null 时不检查。
+	// 分配给节点超过节点超时时间但是不在其中的请求 (例如长轮询的响应丢失) 重新排队
+	Running []string `json:"running"`
+}
+
+// HeartbeatResponse 定义心跳的响应
+type HeartbeatResponse struct {
+	Cancelled []string `json:"cancelled,omitempty"` // 被取消的请求，节点应该停止执行
+}
+
+// Task 定义分发给节点的请求
+type Task struct {
+	ID      string         `json:"id"`
+	Request *model.Request `json:"request"`
+}
+
+// NodeStatus 定义节点的状态
+type NodeStatus struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Capacity     int       `json:"capacity"`
+	Running      int       `json:"running"`
+	RegisterTime time.Time `json:"registerTime"`
+	LastSeen     time.Time `json:"lastSeen"`
+}
+
+// CoordinatorConfig 定义协调节点配置
+type CoordinatorConfig struct {
+	// NodeTimeout 为节点失效的时间，超过该时间没有心跳的节点被移除，其执行中的请求重新排队
+	NodeTimeout time.Duration
+	Logger      *zap.Logger
+}
+
+// Coordinator 保存等待队列并将请求分发给通过长轮询获取请求的节点。
+// 作为 worker 的 Executor 使用，worker 的并行数为所有节点的容量之和
+type Coordinator struct {
+	timeout time.Duration
+	logger  *zap.Logger
+	worker  worker.Worker
+
+	mu      sync.Mutex
+	nodes   map[string]*node
+	pending []*task       // 等待节点获取的请求
+	wake    chan struct{} // 有请求等待获取时关闭
+	done    chan struct{}
+	once    sync.Once
+}
+
+type node struct {
+	NodeStatus
+	tasks     map[string]*task
+	cancelled []string // 执行中被取消的请求，通过下一次心跳通知节点
+}
+
+type task struct {
+	Task
+	result   chan model.Response
+	assigned time.Time // 分配给节点的时间
+}
+
+// NewCoordinator 创建协调节点
+func NewCoordinator(conf CoordinatorConfig) *Coordinator {
+	return &Coordinator{
+		timeout: conf.NodeTimeout,
+		logger:  conf.Logger,
+		nodes:   make(map[string]*node),
+		wake:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Start 开始检查节点心跳，并在节点变化时调整 worker 的并行数
+func (c *Coordinator) Start(w worker.Worker) {
+	c.mu.Lock()
+	c.worker = w
+	c.updateParallelism()
+	c.mu.Unlock()
+
+	go c.checkTimeoutLoop()
+}
+
+// Shutdown 停止检查节点心跳，等待中以及执行中的请求返回 worker.ErrShutdown
+func (c *Coordinator) Shutdown() {
+	c.once.Do(func() {
+		close(c.done)
+	})
+}
+
+// Execute 将请求交给节点执行并等待结果
+func (c *Coordinator) Execute(ctx context.Context, r *worker.Request) worker.Response {
+	req, err := model.ConvertWorkerRequest(r)
+	if err != nil {
+		return worker.Response{RequestID: r.RequestID, Error: err}
+	}
+	id, err := generateID()
+	if err != nil {
+		return worker.Response{RequestID: r.RequestID, Error: err}
+	}
+	t := &task{
+		Task:   Task{ID: id, Request: req},
+		result: make(chan model.Response, 1),
+	}
+	c.mu.Lock()
+	c.pending = append(c.pending, t)
+	c.notify()
+	c.mu.Unlock()
+
+	select {
+	case res := <-t.result:
+		rt, err := model.ConvertModelResponse(res, "")
+		if err != nil {
+			return worker.Response{RequestID: r.RequestID, Error: err}
+		}
+		return rt
+	case <-ctx.Done():
+		c.remove(t)
+		return worker.Response{
+			RequestID: r.RequestID,
+			Error:     fmt.Errorf("cancelled before execute"),
+		}
+	case <-c.done:
+		c.remove(t)
+		return worker.Response{
+			RequestID: r.RequestID,
+			Error:     worker.ErrShutdown,
+		}
+	}
+}
+
+// remove 移除等待中或者执行中的请求，之后节点返回的结果被忽略
+func (c *Coordinator) remove(t *task) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, p := range c.pending {
+		if p == t {
+			c.pending = append(c.pending[:i], c.pending[i+1:]...)
+			return
+		}
+	}
+	for _, n := range c.nodes {
+		if _, ok := n.tasks[t.ID]; ok {
+			delete(n.tasks, t.ID)
+			n.Running--
+			n.cancelled = append(n.cancelled, t.ID)
+			return
+		}
+	}
+}
+
+// Register 注册节点
+func (c *Coordinator) Register(reg Registration) (RegisterResponse, error) {
+	if reg.Capacity <= 0 {
+		return RegisterResponse{}, fmt.Errorf("capacity must be positive")
+	}
+	id, err := generateID()
+	if err != nil {
+		return RegisterResponse{}, err
+	}
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.nodes[id] = &node{
+		NodeStatus: NodeStatus{
+			ID:           id,
+			Name:         reg.Name,
+			Capacity:     reg.Capacity,
+			RegisterTime: now,
+			LastSeen:     now,
+		},
+		tasks: make(map[string]*task),
+	}
+	c.updateParallelism()
+	c.logger.Sugar().Infof("node registered: id=%s name=%s capacity=%d", id, reg.Name, reg.Capacity)
+	return RegisterResponse{ID: id, Heartbeat: c.timeout / 3}, nil
+}
+
+// Deregister 移除节点，其执行中的请求重新排队
+func (c *Coordinator) Deregister(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n, ok := c.nodes[id]
+	if !ok {
+		return ErrUnknownNode
+	}
+	c.removeNode(n)
+	c.logger.Sugar().Infof("node deregistered: id=%s name=%s", id, n.Name)
+	return nil
+}
+
+// Heartbeat 更新节点的心跳时间，重新排队节点没有收到的请求，并返回上次心跳之后被取消的执行中的请求
+func (c *Coordinator) Heartbeat(id string, req HeartbeatRequest) (HeartbeatResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n, ok := c.nodes[id]
+	if !ok {
+		return HeartbeatResponse{}, ErrUnknownNode
+	}
+	now := time.Now()
+	n.LastSeen = now
+	if req.Running != nil {
+		running := make(map[string]bool, len(req.Running))
+		for _, tid := range req.Running {
+			running[tid] = true
+		}
+		var lost []*task
+		for tid, t := range n.tasks {
+			// 长轮询的响应可能仍在传输中，超过节点超时时间之后才认为丢失
+			if !running[tid] && t.assigned.Add(c.timeout).Before(now) {
+				delete(n.tasks, tid)
+				n.Running--
+				lost = append(lost, t)
+			}
+		}
+		if len(lost) > 0 {
+			c.pending = append(lost, c.pending...)
+			c.notify()
+			c.logger.Sugar().Warnf("node %s did not receive %d requests, requeued", id, len(lost))
+		}
+	}
+	rt := HeartbeatResponse{Cancelled: n.cancelled}
+	n.cancelled = nil
+	return rt, nil
+}
+
+// Poll 等待并返回分配给节点的下一个请求，节点已满、在 wait 时间内没有请求或者连接断开时返回 nil
+func (c *Coordinator) Poll(ctx context.Context, id string, wait time.Duration) (*Task, error) {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		c.mu.Lock()
+		n, ok := c.nodes[id]
+		if !ok {
+			c.mu.Unlock()
+			return nil, ErrUnknownNode
+		}
+		n.LastSeen = time.Now()
+		if n.Running < n.Capacity && len(c.pending) > 0 {
+			t := c.pending[0]
+			c.pending = c.pending[1:]
+			t.assigned = time.Now()
+			n.tasks[t.ID] = t
+			n.Running++
+			c.mu.Unlock()
+			return &t.Task, nil
+		}
+		wake := c.wake
+		c.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-timer.C:
+			return nil, nil
+		case <-ctx.Done():
+			return nil, nil
+		}
+	}
+}
+
+// Report 接收节点执行完成的结果
+func (c *Coordinator) Report(id, taskID string, res model.Response) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n, ok := c.nodes[id]
+	if !ok {
+		return ErrUnknownNode
+	}
+	n.LastSeen = time.Now()
+	t, ok := n.tasks[taskID]
+	if !ok {
+		return ErrUnknownTask
+	}
+	delete(n.tasks, taskID)
+	n.Running--
+	// 节点容量空出，唤醒等待的长轮询
+	c.notify()
+	t.result <- res
+	return nil
+}
+
+// Nodes 返回所有节点的状态
+func (c *Coordinator) Nodes() []NodeStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rt := make([]NodeStatus, 0, len(c.nodes))
+	for _, n := range c.nodes {
+		rt = append(rt, n.NodeStatus)
+	}
+	sort.Slice(rt, func(i, j int) bool {
+		return rt[i].RegisterTime.Before(rt[j].RegisterTime)
+	})
+	return rt
+}
+
+func (c *Coordinator) checkTimeoutLoop() {
+	ticker := time.NewTicker(c.timeout / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.checkTimeout()
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *Coordinator) checkTimeout() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for id, n := range c.nodes {
+		if n.LastSeen.Add(c.timeout).Before(now) {
+			c.removeNode(n)
+			c.logger.Sugar().Warnf("node timeout: id=%s name=%s, %d requests requeued", id, n.Name, len(n.tasks))
+		}
+	}
+}
+
+// removeNode 移除节点并将其执行中的请求放回队首，调用时需要持有锁
+func (c *Coordinator) removeNode(n *node) {
+	delete(c.nodes, n.ID)
+	if len(n.tasks) > 0 {
+		requeue := make([]*task, 0, len(n.tasks)+len(c.pending))
+		for _, t := range n.tasks {
+			requeue = append(requeue, t)
+		}
+		c.pending = append(requeue, c.pending...)
+		c.notify()
+	}
+	c.updateParallelism()
+}
+
+// updateParallelism 将 worker 的并行数设置为所有节点的容量之和，调用时需要持有锁
+func (c *Coordinator) updateParallelism() {
+	if c.worker == nil {
+		return
+	}
+	total := 0
+	for _, n := range c.nodes {
+		total += n.Capacity
+	}
+	c.worker.SetParallelism(total)
+}
+
+// notify 唤醒所有等待的长轮询，调用时需要持有锁
+func (c *Coordinator) notify() {
+	close(c.wake)
+	c.wake = make(chan struct{})
+}
+
+func generateID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,517 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/model"
+	"github.com/lxhcaicai/loj-judge/envexec"
+	"github.com/lxhcaicai/loj-judge/filestore"
+	"github.com/lxhcaicai/loj-judge/worker"
+	"go.uber.org/zap"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// 记录文件所在节点的最大数量，超过时删除任意的记录
+const ownerLimit = 1 << 16
+
+// ErrNoPeer 表示没有健康并且满足请求要求的节点
+var ErrNoPeer = errors.New("no available peer")
+
+// errFileNotExists 表示请求引用的文件不存在，不应该因此将节点标记为不健康
+var errFileNotExists = errors.New("file not exists")
+
+// FederationConfig 定义转发配置
+type FederationConfig struct {
+	Peers         []string // 节点的 http 地址
+	APIKey        string   // 节点启用认证时使用的 api key
+	CheckInterval time.Duration
+	FileStore     filestore.FileStore // 本地的文件存储
+	Client        *http.Client
+	Logger        *zap.Logger
+}
+
+// PeerStatus 定义节点的状态
+type PeerStatus struct {
+	Addr      string    `json:"addr"`
+	Healthy   bool      `json:"healthy"`
+	Cgroup    bool      `json:"cgroup"`
+	Languages []string  `json:"languages"` // 节点不提供 /languages 时为 null
+	Waiting   int       `json:"waiting"`   // 节点报告的等待请求数量
+	Inflight  int       `json:"inflight"`  // 转发到节点并且尚未返回的请求数量
+	Error     string    `json:"error,omitempty"`
+	LastCheck time.Time `json:"lastCheck"`
+}
+
+// Federation 将请求通过 /run 转发到其他 executorserver，节点不需要任何修改。
+// 引用了节点上的缓存文件的请求优先转发到该节点，其他情况选择负载最小的节点，
+// 只选择健康并且支持请求所用语言以及 cgroup 的节点
+type Federation struct {
+	interval time.Duration
+	logger   *zap.Logger
+	fs       *remoteFileStore
+	peers    []*peer
+	done     chan struct{}
+	once     sync.Once
+
+	mu     sync.Mutex
+	owners map[string]*peer // 节点产生的文件 id -> 节点
+}
+
+type peer struct {
+	PeerStatus
+	client    *client
+	languages map[string]bool
+	uploaded  map[string]string // 本地的文件 id -> 上传到节点后的文件 id
+}
+
+// NewFederation 创建转发器
+func NewFederation(conf FederationConfig) *Federation {
+	f := &Federation{
+		interval: conf.CheckInterval,
+		logger:   conf.Logger,
+		done:     make(chan struct{}),
+		owners:   make(map[string]*peer),
+	}
+	for _, addr := range conf.Peers {
+		f.peers = append(f.peers, &peer{
+			PeerStatus: PeerStatus{Addr: addr},
+			client:     newClient(addr, conf.APIKey, conf.Client),
+			uploaded:   make(map[string]string),
+		})
+	}
+	f.fs = newRemoteFileStore(conf.FileStore, f.locate, f.remove)
+	return f
+}
+
+// FileStore 返回文件存储，节点产生的文件在本地不存在时从节点下载
+func (f *Federation) FileStore() filestore.FileStore {
+	return f.fs
+}
+
+// Start 检查所有节点后开始定期健康检查
+func (f *Federation) Start() {
+	f.checkAll()
+	go func() {
+		ticker := time.NewTicker(f.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				f.checkAll()
+			case <-f.done:
+				return
+			}
+		}
+	}()
+}
+
+// Shutdown 停止健康检查
+func (f *Federation) Shutdown() {
+	f.once.Do(func() {
+		close(f.done)
+	})
+}
+
+// Peers 返回所有节点的状态
+func (f *Federation) Peers() []PeerStatus {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	rt := make([]PeerStatus, 0, len(f.peers))
+	for _, p := range f.peers {
+		rt = append(rt, p.PeerStatus)
+	}
+	return rt
+}
+
+// Execute 将请求转发到节点执行。节点不可用或者队列已满时尝试其他节点
+func (f *Federation) Execute(ctx context.Context, r *worker.Request) worker.Response {
+	tried := make(map[*peer]bool)
+	lastErr := ErrNoPeer
+	for {
+		// 每次转发时重新转换，文件 id 会被替换为节点上的 id
+		req, err := model.ConvertWorkerRequest(r)
+		if err != nil {
+			return worker.Response{RequestID: r.RequestID, Error: err}
+		}
+		p := f.pick(r, req, tried)
+		if p == nil {
+			return worker.Response{RequestID: r.RequestID, Error: lastErr}
+		}
+		res, err := f.forward(ctx, p, req)
+		f.release(p, err)
+		if err == nil {
+			f.record(p, res)
+			rt, err := model.ConvertModelResponse(res, "")
+			if err != nil {
+				return worker.Response{RequestID: r.RequestID, Error: err}
+			}
+			return rt
+		}
+		if ctx.Err() != nil || errors.Is(err, errFileNotExists) {
+			return worker.Response{RequestID: r.RequestID, Error: err}
+		}
+		var se *statusError
+		switch {
+		case !errors.As(err, &se):
+			// 连接失败，节点在下次健康检查成功之前不再接收请求
+			f.logger.Sugar().Warnf("forward to %s: %v", p.Addr, err)
+		case se.code == http.StatusServiceUnavailable:
+			lastErr = worker.ErrQueueFull
+		default:
+			return worker.Response{RequestID: r.RequestID, Error: fmt.Errorf("%s", se.msg)}
+		}
+		tried[p] = true
+	}
+}
+
+// pick 选择转发的节点并增加其执行中的请求数量，没有可用的节点时返回 nil
+func (f *Federation) pick(r *worker.Request, req *model.Request, tried map[*peer]bool) *peer {
+	var ids []string
+	walkFiles(req, func(cf *model.CmdFile) {
+		if cf.FileID != nil {
+			ids = append(ids, *cf.FileID)
+		}
+	})
+	cgroup := needsCgroup(r)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var (
+		best       *peer
+		bestSticky int
+	)
+	for _, p := range f.peers {
+		if tried[p] || !p.Healthy || (cgroup && !p.Cgroup) || !p.supports(r.Languages) {
+			continue
+		}
+		sticky := 0
+		for _, id := range ids {
+			if f.owners[id] == p {
+				sticky++
+			}
+		}
+		if best == nil || sticky > bestSticky || (sticky == bestSticky && p.load() < best.load()) {
+			best, bestSticky = p, sticky
+		}
+	}
+	if best != nil {
+		best.Inflight++
+	}
+	return best
+}
+
+// release 在转发完成后调用，连接失败的节点被标记为不健康
+func (f *Federation) release(p *peer, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p.Inflight--
+	var se *statusError
+	if err != nil && !errors.As(err, &se) && !errors.Is(err, context.Canceled) && !errors.Is(err, errFileNotExists) {
+		p.Healthy = false
+		p.Error = err.Error()
+	}
+}
+
+// record 记录结果中的缓存文件所在的节点
+func (f *Federation) record(p *peer, res model.Response) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	add := func(results []model.Result) {
+		for _, r := range results {
+			for _, id := range r.FileIDs {
+				if len(f.owners) >= ownerLimit {
+					for k := range f.owners {
+						delete(f.owners, k)
+						break
+					}
+				}
+				f.owners[id] = p
+			}
+		}
+	}
+	add(res.Results)
+	for _, s := range res.Stages {
+		add(s.Results)
+	}
+}
+
+func (f *Federation) locate(id string) *client {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if p, ok := f.owners[id]; ok {
+		return p.client
+	}
+	return nil
+}
+
+// remove 删除节点产生的文件以及上传到节点的副本，返回是否删除了节点产生的文件
+func (f *Federation) remove(id string) bool {
+	f.mu.Lock()
+	owner := f.owners[id]
+	delete(f.owners, id)
+	copies := make(map[*peer]string)
+	for _, p := range f.peers {
+		if uploaded, ok := p.uploaded[id]; ok {
+			copies[p] = uploaded
+			delete(p.uploaded, id)
+		}
+	}
+	f.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), f.interval)
+	defer cancel()
+	for p, uploaded := range copies {
+		if err := p.client.remove(ctx, uploaded); err != nil && !isNotFound(err) {
+			f.logger.Sugar().Debugf("peer %s remove %s: %v", p.Addr, uploaded, err)
+		}
+	}
+	if owner == nil {
+		return false
+	}
+	if err := owner.client.remove(ctx, id); err != nil {
+		if !isNotFound(err) {
+			f.logger.Sugar().Warnf("peer %s remove %s: %v", owner.Addr, id, err)
+		}
+		return false
+	}
+	return true
+}
+
+// forward 将请求中不在节点上的缓存文件上传到节点，然后通过 /run 执行
+func (f *Federation) forward(ctx context.Context, p *peer, req *model.Request) (model.Response, error) {
+	var err error
+	walkFiles(req, func(cf *model.CmdFile) {
+		if cf.FileID == nil || err != nil {
+			return
+		}
+		f.mu.Lock()
+		owner := f.owners[*cf.FileID]
+		f.mu.Unlock()
+		if owner == p {
+			return
+		}
+		var id string
+		if id, err = f.transfer(ctx, p, *cf.FileID); err == nil {
+			cf.FileID = &id
+		}
+	})
+	if err != nil {
+		return model.Response{}, err
+	}
+
+	res := model.Response{RequestID: req.RequestID}
+	switch {
+	case len(req.Subtasks) > 0:
+		var body struct {
+			Stages  []model.StageResult `json:"stages"`
+			Verdict *model.Verdict      `json:"verdict"`
+		}
+		err = p.client.do(ctx, http.MethodPost, "/run", req, &body)
+		res.Stages, res.Verdict = body.Stages, body.Verdict
+	case len(req.Stages) > 0:
+		err = p.client.do(ctx, http.MethodPost, "/run", req, &res.Stages)
+	default:
+		err = p.client.do(ctx, http.MethodPost, "/run", req, &res.Results)
+	}
+	return res, err
+}
+
+// transfer 将文件上传到节点并返回节点上的文件 id，同一文件只上传一次
+func (f *Federation) transfer(ctx context.Context, p *peer, id string) (string, error) {
+	f.mu.Lock()
+	uploaded, ok := p.uploaded[id]
+	f.mu.Unlock()
+	if ok {
+		return uploaded, nil
+	}
+
+	name, file := f.fs.Get(id)
+	if file == nil {
+		return "", fmt.Errorf("%w with id %v", errFileNotExists, id)
+	}
+	r, err := envexec.FileToReader(file)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	uploaded, err = p.client.upload(ctx, name, r)
+	if err != nil {
+		return "", err
+	}
+
+	f.mu.Lock()
+	if len(p.uploaded) >= ownerLimit {
+		for k := range p.uploaded {
+			delete(p.uploaded, k)
+			break
+		}
+	}
+	p.uploaded[id] = uploaded
+	f.mu.Unlock()
+	return uploaded, nil
+}
+
+func (f *Federation) checkAll() {
+	var wg sync.WaitGroup
+	for _, p := range f.peers {
+		wg.Add(1)
+		go func(p *peer) {
+			defer wg.Done()
+			f.check(p)
+		}(p)
+	}
+	wg.Wait()
+}
+
+// check 通过 /config 检查节点是否健康并获取节点的能力。
+// /languages 以及 /queue 只作为参考，节点没有提供时不按语言过滤并且认为节点空闲
+func (f *Federation) check(p *peer) {
+	ctx, cancel := context.WithTimeout(context.Background(), f.interval)
+	defer cancel()
+
+	var (
+		conf struct {
+			RunnerConfig struct {
+				CgroupType int `json:"cgroupType"`
+			} `json:"runnerConfig"`
+		}
+		languages []struct {
+			Name string `json:"name"`
+		}
+		queue model.QueueStats
+	)
+	err := p.client.do(ctx, http.MethodGet, "/config", nil, &conf)
+	if err != nil {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		p.LastCheck = time.Now()
+		if p.Healthy || p.Error == "" {
+			f.logger.Sugar().Warnf("peer %s is unhealthy: %v", p.Addr, err)
+		}
+		p.Healthy = false
+		p.Error = err.Error()
+		return
+	}
+	if err := p.client.do(ctx, http.MethodGet, "/languages", nil, &languages); err != nil {
+		f.logger.Sugar().Debugf("peer %s languages: %v", p.Addr, err)
+		languages = nil
+	}
+	if err := p.client.do(ctx, http.MethodGet, "/queue", nil, &queue); err != nil {
+		f.logger.Sugar().Debugf("peer %s queue: %v", p.Addr, err)
+		queue = model.QueueStats{}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p.LastCheck = time.Now()
+	if !p.Healthy {
+		// 节点不可用期间可能重启，之前上传的文件不一定还在
+		f.logger.Sugar().Infof("peer %s is healthy", p.Addr)
+		p.uploaded = make(map[string]string)
+	}
+	p.Healthy = true
+	p.Error = ""
+	p.Cgroup = conf.RunnerConfig.CgroupType != 0
+	p.languages = nil
+	p.Languages = nil
+	if languages != nil {
+		p.languages = make(map[string]bool, len(languages))
+		p.Languages = make([]string, 0, len(languages))
+		for _, l := range languages {
+			p.languages[l.Name] = true
+			p.Languages = append(p.Languages, l.Name)
+		}
+	}
+	p.Waiting = 0
+	for _, n := range queue.Waiting {
+		p.Waiting += n
+	}
+}
+
+// load 为节点报告的等待数量加上转发中的请求数量
+func (p *peer) load() int {
+	return p.Waiting + p.Inflight
+}
+
+// supports 返回节点是否支持请求所用的语言，节点没有提供语言列表时不过滤
+func (p *peer) supports(languages []string) bool {
+	if p.languages == nil {
+		return true
+	}
+	for _, l := range languages {
+		if !p.languages[l] {
+			return false
+		}
+	}
+	return true
+}
+
+// needsCgroup 返回请求是否使用了需要 cgroup 的限制
+func needsCgroup(r *worker.Request) bool {
+	var check func(c worker.Cmd) bool
+	check = func(c worker.Cmd) bool {
+		if c.CPURateLimit > 0 || c.CPUSetLimit != "" {
+			return true
+		}
+		return c.Checker != nil && check(c.Checker.Cmd)
+	}
+	for _, c := range r.Cmd {
+		if check(c) {
+			return true
+		}
+	}
+	for _, s := range r.Stages {
+		for _, c := range s.Cmd {
+			if check(c) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// walkFiles 对请求中的每个文件调用 fn，fn 可以修改文件
+func walkFiles(req *model.Request, fn func(*model.CmdFile)) {
+	var walk func(c *model.Cmd)
+	walk = func(c *model.Cmd) {
+		for _, f := range c.Files {
+			if f != nil {
+				fn(f)
+			}
+		}
+		for k, f := range c.CopyIn {
+			fn(&f)
+			c.CopyIn[k] = f
+		}
+		if c.Compare != nil && c.Compare.Answer != nil {
+			fn(c.Compare.Answer)
+		}
+		if c.Checker != nil {
+			if c.Checker.Input != nil {
+				fn(c.Checker.Input)
+			}
+			if c.Checker.Answer != nil {
+				fn(c.Checker.Answer)
+			}
+			walk(&c.Checker.Cmd)
+		}
+	}
+	for i := range req.Cmd {
+		walk(&req.Cmd[i])
+	}
+	for _, s := range req.Stages {
+		for i := range s.Cmd {
+			walk(&s.Cmd[i])
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package cluster_test
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/cluster"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/model"
+	"github.com/lxhcaicai/loj-judge/envexec"
+	"github.com/lxhcaicai/loj-judge/filestore"
+	"github.com/lxhcaicai/loj-judge/worker"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newBaselinePeer 模拟只提供 /config 以及 /run 的 executorserver
+func newBaselinePeer(t *testing.T) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/config", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"runnerConfig": gin.H{"cgroupType": 0}})
+	})
+	r.POST("/run", func(c *gin.Context) {
+		c.JSON(http.StatusOK, []model.Result{{Status: model.Status(envexec.StatusAccepted)}})
+	})
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFederationBaselinePeer(t *testing.T) {
+	peer := newBaselinePeer(t)
+	fed := cluster.NewFederation(cluster.FederationConfig{
+		Peers:         []string{peer.URL},
+		CheckInterval: time.Hour,
+		FileStore:     filestore.NewFileLocalStore(t.TempDir()),
+		Logger:        zap.NewNop(),
+	})
+	fed.Start()
+	defer fed.Shutdown()
+
+	// 缺少 /languages 以及 /queue 不影响节点的健康状态
+	st := fed.Peers()
+	if len(st) != 1 || !st[0].Healthy || st[0].Languages != nil || st[0].Waiting != 0 {
+		t.Fatalf("expected healthy peer without language filter, got %+v", st)
+	}
+
+	rt := fed.Execute(context.Background(), &worker.Request{
+		RequestID: "req",
+		Cmd:       []worker.Cmd{{Args: []string{"true"}}},
+		Languages: []string{"cpp"},
+	})
+	if rt.Error != nil || len(rt.Results) != 1 || rt.Results[0].Status != envexec.StatusAccepted {
+		t.Fatalf("unexpected response: %+v", rt)
+	}
+}
+
+// newFilePeer 模拟返回缓存文件 remote 并且记录删除的文件的节点，上传的文件 id 为 uploaded
+func newFilePeer(t *testing.T) (*httptest.Server, func() []string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	var (
+		mu      sync.Mutex
+		deleted []string
+	)
+	r := gin.New()
+	r.GET("/config", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"runnerConfig": gin.H{"cgroupType": 0}})
+	})
+	r.POST("/run", func(c *gin.Context) {
+		c.JSON(http.StatusOK, []model.Result{{
+			Status:  model.Status(envexec.StatusAccepted),
+			FileIDs: map[string]string{"out": "remote"},
+		}})
+	})
+	r.POST("/file", func(c *gin.Context) {
+		c.JSON(http.StatusOK, "uploaded")
+	})
+	r.DELETE("/file/:fid", func(c *gin.Context) {
+		mu.Lock()
+		deleted = append(deleted, c.Param("fid"))
+		mu.Unlock()
+		c.JSON(http.StatusOK, nil)
+	})
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), deleted...)
+	}
+}
+
+func TestFederationRemove(t *testing.T) {
+	peer, deleted := newFilePeer(t)
+	fed := cluster.NewFederation(cluster.FederationConfig{
+		Peers:         []string{peer.URL},
+		CheckInterval: time.Hour,
+		FileStore:     filestore.NewFileLocalStore(t.TempDir()),
+		Logger:        zap.NewNop(),
+	})
+	fed.Start()
+	defer fed.Shutdown()
+	fs := fed.FileStore()
+
+	f, err := fs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("1")
+	f.Close()
+	local, err := fs.Add("in", f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rt := fed.Execute(context.Background(), &worker.Request{
+		RequestID: "req",
+		Cmd: []worker.Cmd{{
+			Args:   []string{"true"},
+			CopyIn: map[string]worker.CmdFile{"in": &worker.CachedFile{FileID: local}},
+		}},
+	})
+	if rt.Error != nil || len(rt.Results) != 1 || rt.Results[0].FileIDs["out"] != "remote" {
+		t.Fatalf("unexpected response: %+v", rt)
+	}
+
+	// 删除本地文件时同时删除上传到节点的副本
+	if !fs.Remove(local) {
+		t.Fatal("expected local file removed")
+	}
+	// 节点产生的文件转发到节点删除，之后不再记录文件所在的节点
+	if !fs.Remove("remote") {
+		t.Fatal("expected remote file removed")
+	}
+	if fs.Remove("remote") {
+		t.Fatal("expected removed remote file not found")
+	}
+	if got := deleted(); len(got) != 2 || got[0] != "uploaded" || got[1] != "remote" {
+		t.Fatalf("expected uploaded and remote deleted on peer, got %v", got)
+	}
+}
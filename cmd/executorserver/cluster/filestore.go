@@ -0,0 +1,104 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"github.com/lxhcaicai/loj-judge/envexec"
+	"github.com/lxhcaicai/loj-judge/filestore"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const downloadTimeout = time.Minute
+
+var _ filestore.FileStore = &remoteFileStore{}
+
+// remoteFileStore 在本地文件存储中找不到缓存文件时从远端 (协调节点或者文件所在的节点) 下载，
+// 下载的文件保存在本地
+type remoteFileStore struct {
+	filestore.FileStore
+	locate func(id string) *client // 返回文件所在的远端，未知时返回 nil
+	remove func(id string) bool    // 删除远端的文件，为 nil 时只删除本地的文件
+
+	mu  sync.Mutex
+	ids map[string]string // 远端的文件 id -> 本地文件 id
+}
+
+// NewFileStore 创建按需从协调节点下载缓存文件的文件存储
+func NewFileStore(fs filestore.FileStore, coordinator, key string, c *http.Client) filestore.FileStore {
+	cl := newClient(coordinator, key, c)
+	return newRemoteFileStore(fs, func(string) *client { return cl }, nil)
+}
+
+func newRemoteFileStore(fs filestore.FileStore, locate func(id string) *client, remove func(id string) bool) *remoteFileStore {
+	return &remoteFileStore{
+		FileStore: fs,
+		locate:    locate,
+		remove:    remove,
+		ids:       make(map[string]string),
+	}
+}
+
+func (s *remoteFileStore) Get(id string) (string, envexec.File) {
+	s.mu.Lock()
+	local, ok := s.ids[id]
+	s.mu.Unlock()
+	if ok {
+		if name, f := s.FileStore.Get(local); f != nil {
+			return name, f
+		}
+	}
+	// 节点自身产生的文件 (例如阶段之间传递的 copyOutCached)
+	if name, f := s.FileStore.Get(id); f != nil {
+		return name, f
+	}
+
+	local, err := s.fetch(id)
+	if err != nil {
+		return "", nil
+	}
+	s.mu.Lock()
+	s.ids[id] = local
+	s.mu.Unlock()
+	return s.FileStore.Get(local)
+}
+
+// Remove 删除本地的文件以及下载的副本，并删除远端的文件
+func (s *remoteFileStore) Remove(id string) bool {
+	s.mu.Lock()
+	local, ok := s.ids[id]
+	delete(s.ids, id)
+	s.mu.Unlock()
+
+	removed := s.FileStore.Remove(id)
+	if ok && s.FileStore.Remove(local) {
+		removed = true
+	}
+	if s.remove != nil && s.remove(id) {
+		removed = true
+	}
+	return removed
+}
+
+func (s *remoteFileStore) fetch(id string) (string, error) {
+	c := s.locate(id)
+	if c == nil {
+		return "", fmt.Errorf("file not exists with id %v", id)
+	}
+	f, err := s.FileStore.New()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), downloadTimeout)
+	defer cancel()
+	name, err := c.download(ctx, id, f)
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return s.FileStore.Add(name, f.Name())
+}
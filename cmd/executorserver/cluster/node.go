@@ -0,0 +1,322 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/model"
+	"github.com/lxhcaicai/loj-judge/envexec"
+	"github.com/lxhcaicai/loj-judge/filestore"
+	"github.com/lxhcaicai/loj-judge/worker"
+	"go.uber.org/zap"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	pollWait     = 30 * time.Second
+	retryBackoff = time.Second
+	reportRetry  = 3
+)
+
+// NodeConfig 定义节点配置
+type NodeConfig struct {
+	Coordinator string // 协调节点的 http 地址
+	APIKey      string // 协调节点启用认证时使用的 api key
+	Name        string
+	Capacity    int // 同时从协调节点获取的请求数量
+	Worker      worker.Worker
+	FileStore   filestore.FileStore
+	SrcPrefix   []string
+	Client      *http.Client
+	Logger      *zap.Logger
+}
+
+// Node 向协调节点注册，通过长轮询获取请求并在本地的 worker 中执行，然后返回结果
+type Node struct {
+	conf   NodeConfig
+	client *client
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup // 长轮询以及心跳
+	tasks  sync.WaitGroup // 执行中的请求
+
+	mu        sync.Mutex
+	id        string
+	heartbeat time.Duration
+	running   map[string]runningTask // 请求 id -> 执行中的请求
+}
+
+type runningTask struct {
+	node   string // 获取请求时的节点 id
+	cancel context.CancelFunc
+}
+
+// NewNode 创建节点
+func NewNode(conf NodeConfig) *Node {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Node{
+		conf:    conf,
+		client:  newClient(conf.Coordinator, conf.APIKey, conf.Client),
+		ctx:     ctx,
+		cancel:  cancel,
+		running: make(map[string]runningTask),
+	}
+}
+
+// Start 开始获取请求，协调节点不可用时不断重试
+func (n *Node) Start() {
+	n.wg.Add(n.conf.Capacity + 1)
+	for i := 0; i < n.conf.Capacity; i++ {
+		go n.pollLoop()
+	}
+	go n.heartbeatLoop()
+}
+
+// Shutdown 停止获取请求并等待执行中的请求完成，然后注销节点，
+// 未完成的请求由协调节点重新分发
+func (n *Node) Shutdown(ctx context.Context) error {
+	n.cancel()
+	finished := make(chan struct{})
+	go func() {
+		n.wg.Wait()
+		n.tasks.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-ctx.Done():
+	}
+
+	n.mu.Lock()
+	id := n.id
+	n.mu.Unlock()
+	if id == "" {
+		return nil
+	}
+	return n.client.deregister(ctx, id)
+}
+
+// nodeID 返回当前的节点 id，尚未注册时注册
+func (n *Node) nodeID() (string, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.id != "" {
+		return n.id, nil
+	}
+	rt, err := n.client.register(n.ctx, Registration{
+		Name:     n.conf.Name,
+		Capacity: n.conf.Capacity,
+	})
+	if err != nil {
+		return "", err
+	}
+	n.id, n.heartbeat = rt.ID, rt.Heartbeat
+	n.conf.Logger.Sugar().Infof("registered to coordinator %s as %s", n.conf.Coordinator, rt.ID)
+	return n.id, nil
+}
+
+// reset 在协调节点不认识该节点 (例如因为心跳超时被移除) 时清除 id 以便重新注册。
+// 协调节点已经将该节点执行中的请求重新排队，因此取消这些请求
+func (n *Node) reset(id string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.id == id {
+		n.id = ""
+	}
+	for _, t := range n.running {
+		if t.node == id {
+			t.cancel()
+		}
+	}
+}
+
+// cancelTasks 取消协调节点通知的已经被取消或者重新分发的请求
+func (n *Node) cancelTasks(ids []string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, id := range ids {
+		if t, ok := n.running[id]; ok {
+			t.cancel()
+		}
+	}
+}
+
+// runningTasks 返回以节点 id 获取的执行中的请求
+func (n *Node) runningTasks(id string) []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	rt := make([]string, 0, len(n.running))
+	for tid, t := range n.running {
+		if t.node == id {
+			rt = append(rt, tid)
+		}
+	}
+	return rt
+}
+
+func (n *Node) pollLoop() {
+	defer n.wg.Done()
+	for n.ctx.Err() == nil {
+		id, err := n.nodeID()
+		if err == nil {
+			var t *Task
+			t, err = n.client.poll(n.ctx, id, pollWait)
+			if err == nil {
+				if t != nil {
+					n.run(id, t)
+				}
+				continue
+			}
+			if isNotFound(err) {
+				n.reset(id)
+				continue
+			}
+		}
+		if n.ctx.Err() != nil {
+			return
+		}
+		n.conf.Logger.Sugar().Warn("poll coordinator: ", err)
+		n.sleep(retryBackoff)
+	}
+}
+
+func (n *Node) heartbeatLoop() {
+	defer n.wg.Done()
+	for {
+		n.mu.Lock()
+		id, interval := n.id, n.heartbeat
+		n.mu.Unlock()
+		if interval <= 0 {
+			interval = retryBackoff
+		}
+		if !n.sleep(interval) {
+			return
+		}
+		if id == "" {
+			continue
+		}
+		rt, err := n.client.heartbeat(n.ctx, id, HeartbeatRequest{Running: n.runningTasks(id)})
+		switch {
+		case isNotFound(err):
+			n.reset(id)
+		case err == nil:
+			n.cancelTasks(rt.Cancelled)
+		}
+	}
+}
+
+// sleep 等待一段时间，节点停止时返回 false
+func (n *Node) sleep(d time.Duration) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-n.ctx.Done():
+		return false
+	}
+}
+
+// run 在本地执行请求并返回结果。停机时未执行的请求不返回结果，由协调节点重新分发。
+// 被协调节点取消的请求停止执行并且不返回结果
+func (n *Node) run(id string, t *Task) {
+	n.tasks.Add(1)
+	defer n.tasks.Done()
+
+	// 停机时等待执行中的请求完成，因此不使用 n.ctx
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	n.mu.Lock()
+	n.running[t.ID] = runningTask{node: id, cancel: cancel}
+	n.mu.Unlock()
+	defer func() {
+		n.mu.Lock()
+		delete(n.running, t.ID)
+		n.mu.Unlock()
+	}()
+
+	res := n.execute(ctx, t)
+	if res == nil || ctx.Err() != nil {
+		return
+	}
+	for i := 0; i < reportRetry; i++ {
+		err := n.client.report(context.Background(), id, t.ID, res)
+		if err == nil || isNotFound(err) {
+			return
+		}
+		n.conf.Logger.Sugar().Warnf("report task %s: %v", t.ID, err)
+		time.Sleep(retryBackoff)
+	}
+}
+
+func (n *Node) execute(ctx context.Context, t *Task) *model.Response {
+	r, err := model.ConvertRequest(t.Request, n.conf.SrcPrefix)
+	if err != nil {
+		return &model.Response{RequestID: t.Request.RequestID, ErrorMsg: err.Error()}
+	}
+	r.Persistent = true
+	rtCh, _ := n.conf.Worker.Submit(ctx, r)
+	rt := <-rtCh
+	if errors.Is(rt.Error, worker.ErrShutdown) || errors.Is(rt.Error, worker.ErrDraining) {
+		return nil
+	}
+	res, err := model.ConvertResponse(rt, false)
+	if err != nil {
+		return &model.Response{RequestID: t.Request.RequestID, ErrorMsg: err.Error()}
+	}
+	if err := n.uploadFiles(&res); err != nil {
+		return &model.Response{RequestID: t.Request.RequestID, ErrorMsg: err.Error()}
+	}
+	return &res
+}
+
+// uploadFiles 将结果中节点产生的缓存文件上传到协调节点，替换为协调节点上的文件 id 并删除本地的文件。
+// 不在本地的文件已经在协调节点上，不再上传
+func (n *Node) uploadFiles(res *model.Response) error {
+	local := n.conf.FileStore
+	if rs, ok := local.(*remoteFileStore); ok {
+		local = rs.FileStore
+	}
+	var err error
+	uploaded := make(map[string]string)
+	upload := func(results []model.Result) {
+		for _, r := range results {
+			for name, id := range r.FileIDs {
+				if newID, ok := uploaded[id]; ok {
+					r.FileIDs[name] = newID
+					continue
+				}
+				_, f := local.Get(id)
+				if f == nil {
+					continue
+				}
+				if err == nil {
+					var newID string
+					if newID, err = n.upload(name, f); err == nil {
+						uploaded[id] = newID
+						r.FileIDs[name] = newID
+					}
+				}
+				// 上传失败时返回错误，结果不再引用本地的文件，同样删除
+				local.Remove(id)
+			}
+		}
+	}
+	upload(res.Results)
+	for _, s := range res.Stages {
+		upload(s.Results)
+	}
+	return err
+}
+
+func (n *Node) upload(name string, f envexec.File) (string, error) {
+	rd, err := envexec.FileToReader(f)
+	if err != nil {
+		return "", err
+	}
+	defer rd.Close()
+	return n.client.upload(context.Background(), name, rd)
+}
@@ -17,6 +17,7 @@ type Config struct {
 	NetShare           bool   `flagUsage:"share net namespace with host"`
 	MountConf          string `flagUsage:"specifies mount configuration file" default:"mount.yaml"`
 	SeccompConf        string `flagUsage:"specifies seccomp filter" default:"seccomp.yaml"`
+	LanguageConf       string `flagUsage:"specifies language presets configuration file" default:"languages.yaml"`
 	Parallelism        int    `flagUsage:"control the # of concurrency execution (default equal to number of cpu)"`
 	CgroupPrefix       string `flagUsage:"control cgroup prefix" default:"executor_server"`
 	ContainerCredStart int    `flagUsage:"control the start uid&gid for container (0 uses unprivileged root)" default:"0"`
@@ -27,6 +28,9 @@ type Config struct {
 
 	// runner limit
 	FileTimeout              time.Duration `flagUsage:"specified timeout for filestore files"`
+	JobResultTimeout         time.Duration `flagUsage:"specified how long results of finished async jobs are kept" default:"10m"`
+	DurableJobs              bool          `flagUsage:"persist async jobs and their results in job-log-dir so that they are resumed after restart"`
+	JobLogDir                string        `flagUsage:"specifies directory of the durable job log (default jobs under dir, required when dir is not set)"`
 	Cpuset                   string        `flagUsage:"control the usage of cpuset for all containerd process"`
 	CPUCfsPeriod             time.Duration `flagUsage:"set cpu.cfs_period" default:"100ms"`
 	EnableCPURate            bool          `flagUsage:"enable cpu cgroup rate control"`
@@ -36,10 +40,45 @@ type Config struct {
 	CopyOutLimit             *envexec.Size `flagUsage:"specifies default file copy out max" default:"256m"`
 	OpenFileLimit            int           `flagUsage:"specifies max open file count" default:"256"`
 
+	// compile cache
+	CompileCacheSize int           `flagUsage:"specifies max entries of the compile result cache (0 to disable)" default:"1024"`
+	CompileCacheTTL  time.Duration `flagUsage:"specifies how long a compile result is cached" default:"1h"`
+	MaxRetry         int           `flagUsage:"specifies max retries with a fresh environment when execution fails with internal error" default:"2"`
+	QueueWeights     []string      `flagUsage:"specifies fair scheduling weights of queue keys in key=weight form (default weight 1)"`
+	QueueConcurrency []string      `flagUsage:"specifies max concurrent requests of queue keys in key=limit form"`
+	MemoryBudget     *envexec.Size `flagUsage:"specifies max total memory limit of running requests, including extra memory limit (0 for unlimited)" default:"0"`
+
 	// server config
-	HTTPAddr      string `flagUsage:"specifies the http binding address"`
+	HTTPAddr      string `flagUsage:"specifies the http binding address (host:port, unix:/path or systemd:name for socket activation)"`
+	EnableGRPC    bool   `flagUsage:"enable gRPC endpoint"`
+	GRPCAddr      string `flagUsage:"specifies the grpc binding address (host:port, unix:/path or systemd:name for socket activation)"`
+	GRPCMsgSize   int    `flagUsage:"specifies the maximum grpc message size in MiB" default:"64"`
+	TLSCert       string `flagUsage:"specifies the TLS certificate file, enables TLS for http and grpc with tls-key (reloaded when changed)"`
+	TLSKey        string `flagUsage:"specifies the TLS private key file"`
+	TLSClientCA   string `flagUsage:"specifies the CA file to require and verify client certificates (mutual TLS)"`
 	EnableDebug   bool   `flagUsage:"enable debug endpoint"`
 	EnableMetrics bool   `flagUsage:"enable promethus metrics endpoint"`
+	EnableAdmin   bool   `flagUsage:"enable worker admin endpoint to change parallelism, pause and drain at runtime"`
+	TenantConf    string `flagUsage:"specifies tenants configuration file with api keys and quotas, all http and gRPC requests must be authenticated when set"`
+
+	// unix socket listener
+	UnixSocketMode  string `flagUsage:"specifies file mode of unix socket listeners in octal" default:"0660"`
+	UnixSocketGroup string `flagUsage:"specifies group name or id owning unix socket listeners"`
+
+	// webhook config
+	WebhookRetry   int           `flagUsage:"specifies max retry count for failed webhook deliveries" default:"3"`
+	WebhookTimeout time.Duration `flagUsage:"specifies timeout for each webhook delivery" default:"10s"`
+
+	// cluster config
+	Coordinator     bool          `flagUsage:"run as coordinator that dispatches requests to worker nodes registered over http"`
+	CoordinatorAddr string        `flagUsage:"run as worker node that fetches requests from the coordinator http address (example: -coordinator-addr=http://judge:6060)"`
+	NodeName        string        `flagUsage:"specifies node name reported to the coordinator (default hostname)"`
+	NodeTimeout     time.Duration `flagUsage:"specifies time without heartbeat after which a node is removed and its requests are requeued" default:"30s"`
+	APIKey          string        `flagUsage:"specifies api key sent to the coordinator or peers that require authentication"`
+
+	// federation config
+	Peers             []string      `flagUsage:"forward requests to peer executorservers at the http addresses, -parallelism limits concurrent forwards (example: -peers=http://a:5050,http://b:5050)"`
+	PeerCheckInterval time.Duration `flagUsage:"specifies interval of peer health checks" default:"5s"`
 
 	// logger config
 	Release bool `flagUsage:"release level of logs"`
@@ -65,8 +104,10 @@ func (c *Config) Load() error {
 	if os.Getpid() == 1 {
 		c.Release = true
 		c.HTTPAddr = ":6060"
+		c.GRPCAddr = ":5051"
 	} else {
 		c.HTTPAddr = "localhost:6060"
+		c.GRPCAddr = "localhost:5051"
 	}
 	if c.Parallelism <= 0 {
 		c.Parallelism = runtime.NumCPU()
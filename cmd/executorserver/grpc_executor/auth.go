@@ -0,0 +1,94 @@
+package grpcexecutor
+
+import (
+	"context"
+	"errors"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/model"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/tenant"
+	"github.com/lxhcaicai/loj-judge/worker"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"strings"
+)
+
+type tenantContextKey struct{}
+
+// AuthUnaryInterceptor 通过 metadata 中的 authorization: Bearer <api key> 认证租户，
+// 与 http 接口使用相同的租户配置
+func AuthUnaryInterceptor(reg *tenant.Registry) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		t, err := authenticate(ctx, reg)
+		if err != nil {
+			return nil, err
+		}
+		return handler(context.WithValue(ctx, tenantContextKey{}, t), req)
+	}
+}
+
+// AuthStreamInterceptor 为流式调用认证租户
+func AuthStreamInterceptor(reg *tenant.Registry) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		t, err := authenticate(ss.Context(), reg)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &tenantStream{
+			ServerStream: ss,
+			ctx:          context.WithValue(ss.Context(), tenantContextKey{}, t),
+		})
+	}
+}
+
+// tenantStream 在流的 context 中保存认证的租户
+type tenantStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *tenantStream) Context() context.Context {
+	return s.ctx
+}
+
+func authenticate(ctx context.Context, reg *tenant.Registry) (*tenant.Tenant, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get("authorization") {
+		key, ok := strings.CutPrefix(v, "Bearer ")
+		if !ok {
+			continue
+		}
+		if t := reg.Lookup(strings.TrimSpace(key)); t != nil {
+			return t, nil
+		}
+		return nil, status.Error(codes.Unauthenticated, "invalid api key")
+	}
+	return nil, status.Error(codes.Unauthenticated, "api key not provided")
+}
+
+// tenantOf 返回调用认证的租户，未启用认证时返回 nil
+func tenantOf(ctx context.Context) *tenant.Tenant {
+	t, _ := ctx.Value(tenantContextKey{}).(*tenant.Tenant)
+	return t
+}
+
+// tenantError 超过租户限制或者引用本地文件时返回 PermissionDenied，并发执行数量或者文件存储配额达到上限时返回
+// ResourceExhausted，其他错误返回 code
+func tenantError(err error, code codes.Code) error {
+	switch {
+	case errors.Is(err, tenant.ErrLimitExceeded), errors.Is(err, tenant.ErrLocalFile):
+		code = codes.PermissionDenied
+	case errors.Is(err, tenant.ErrTooManyRuns), errors.Is(err, tenant.ErrQuotaExceeded):
+		code = codes.ResourceExhausted
+	}
+	return status.Error(code, err.Error())
+}
+
+// cachedFiles 返回只包含缓存文件 id 的结果，用于将输出文件计入租户的文件存储用量
+func cachedFiles(rt worker.Response) model.Response {
+	res := model.Response{Results: make([]model.Result, 0, len(rt.Results))}
+	for _, r := range rt.Results {
+		res.Results = append(res.Results, model.Result{FileIDs: r.FileIDs})
+	}
+	return res
+}
@@ -0,0 +1,112 @@
+package grpcexecutor
+
+import (
+	"context"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/tenant"
+	"github.com/lxhcaicai/loj-judge/filestore"
+	"github.com/lxhcaicai/loj-judge/pb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"testing"
+)
+
+func newTestServer(t *testing.T) (*execServer, *tenant.Registry) {
+	t.Helper()
+	fs := filestore.NewFileLocalStore(t.TempDir())
+	reg, err := tenant.NewRegistry([]tenant.Tenant{
+		{Name: "a", Keys: []string{"key"}, FileQuota: 4},
+		{Name: "b", Keys: []string{"other"}},
+	}, fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(nil, fs, nil, zap.NewNop()).(*execServer), reg
+}
+
+// call 以 api key 通过认证拦截器调用 fn，key 为空时不发送
+func call[T any](reg *tenant.Registry, key string, fn func(ctx context.Context) (T, error)) (T, error) {
+	ctx := context.Background()
+	if key != "" {
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+key))
+	}
+	var rt T
+	_, err := AuthUnaryInterceptor(reg)(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, _ any) (any, error) {
+		var err error
+		rt, err = fn(ctx)
+		return rt, err
+	})
+	return rt, err
+}
+
+func add(e *execServer, reg *tenant.Registry, key, content string) (*pb.FileID, error) {
+	return call(reg, key, func(ctx context.Context) (*pb.FileID, error) {
+		return e.FileAdd(ctx, &pb.FileContent{Name: "a", Content: []byte(content)})
+	})
+}
+
+func TestAuthUnaryInterceptor(t *testing.T) {
+	e, reg := newTestServer(t)
+
+	for key, code := range map[string]codes.Code{"": codes.Unauthenticated, "invalid": codes.Unauthenticated} {
+		if _, err := add(e, reg, key, "1"); status.Code(err) != code {
+			t.Fatalf("key %q: expected %v, got %v", key, code, err)
+		}
+	}
+	if len(e.fs.List()) != 0 {
+		t.Fatal("expected no file added without authentication")
+	}
+
+	// 上传的文件计入租户的文件存储配额
+	if _, err := add(e, reg, "key", "123"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := add(e, reg, "key", "45"); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected quota exceeded, got %v", err)
+	}
+	if st := reg.Lookup("key").Status(); st.FileUsage != 3 {
+		t.Fatalf("expected 3 bytes used, got %d", st.FileUsage)
+	}
+}
+
+func TestFileTenantScope(t *testing.T) {
+	e, reg := newTestServer(t)
+	id, err := add(e, reg, "key", "123")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 其他租户看不到也不能访问该文件
+	list, err := call(reg, "other", func(ctx context.Context) (*pb.FileListType, error) {
+		return e.FileList(ctx, &emptypb.Empty{})
+	})
+	if err != nil || len(list.GetFileIDs()) != 0 {
+		t.Fatalf("expected no file listed for other tenant, got %v %v", list.GetFileIDs(), err)
+	}
+	if _, err := call(reg, "other", func(ctx context.Context) (*pb.FileContent, error) {
+		return e.FileGet(ctx, id)
+	}); status.Code(err) != codes.NotFound {
+		t.Fatalf("expected not found for other tenant, got %v", err)
+	}
+	if _, err := call(reg, "other", func(ctx context.Context) (*emptypb.Empty, error) {
+		return e.FileDelete(ctx, id)
+	}); status.Code(err) != codes.NotFound {
+		t.Fatalf("expected not found for other tenant, got %v", err)
+	}
+
+	// 创建文件的租户可以访问
+	fc, err := call(reg, "key", func(ctx context.Context) (*pb.FileContent, error) {
+		return e.FileGet(ctx, id)
+	})
+	if err != nil || string(fc.GetContent()) != "123" {
+		t.Fatalf("expected file content, got %q %v", fc.GetContent(), err)
+	}
+	if _, err := call(reg, "key", func(ctx context.Context) (*emptypb.Empty, error) {
+		return e.FileDelete(ctx, id)
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,85 @@
+package grpcexecutor
+
+import (
+	"context"
+	"github.com/lxhcaicai/loj-judge/envexec"
+	"github.com/lxhcaicai/loj-judge/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"io"
+)
+
+// FileList 返回租户可以访问的文件
+func (e *execServer) FileList(c context.Context, n *emptypb.Empty) (*pb.FileListType, error) {
+	ids := e.fs.List()
+	t := tenantOf(c)
+	for id := range ids {
+		if !t.CanAccessFile(id) {
+			delete(ids, id)
+		}
+	}
+	return &pb.FileListType{
+		FileIDs: ids,
+	}, nil
+}
+
+func (e *execServer) FileGet(c context.Context, f *pb.FileID) (*pb.FileContent, error) {
+	// 其他租户的文件视为不存在
+	if !tenantOf(c).CanAccessFile(f.GetFileID()) {
+		return nil, status.Errorf(codes.NotFound, "file not exists with id %v", f.GetFileID())
+	}
+	name, file := e.fs.Get(f.GetFileID())
+	if file == nil {
+		return nil, status.Errorf(codes.NotFound, "file not exists with id %v", f.GetFileID())
+	}
+	r, err := envexec.FileToReader(file)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	defer r.Close()
+
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &pb.FileContent{
+		Name:    name,
+		Content: content,
+	}, nil
+}
+
+func (e *execServer) FileAdd(c context.Context, fc *pb.FileContent) (*pb.FileID, error) {
+	t := tenantOf(c)
+	if err := t.CheckUpload(int64(len(fc.GetContent()))); err != nil {
+		return nil, tenantError(err, codes.Internal)
+	}
+	f, err := e.fs.New()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	defer f.Close()
+
+	if _, err := f.Write(fc.GetContent()); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	fileID, err := e.fs.Add(fc.GetName(), f.Name())
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	t.AddFile(fileID)
+	return &pb.FileID{
+		FileID: fileID,
+	}, nil
+}
+
+func (e *execServer) FileDelete(c context.Context, f *pb.FileID) (*emptypb.Empty, error) {
+	if !tenantOf(c).CanAccessFile(f.GetFileID()) {
+		return nil, status.Errorf(codes.NotFound, "file id does not exists for %v", f.GetFileID())
+	}
+	ok := e.fs.Remove(f.GetFileID())
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "file id does not exists for %v", f.GetFileID())
+	}
+	return &emptypb.Empty{}, nil
+}
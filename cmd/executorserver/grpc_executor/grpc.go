@@ -0,0 +1,269 @@
+package grpcexecutor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/cluster"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/model"
+	"github.com/lxhcaicai/loj-judge/envexec"
+	"github.com/lxhcaicai/loj-judge/filestore"
+	"github.com/lxhcaicai/loj-judge/pb"
+	"github.com/lxhcaicai/loj-judge/worker"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"os"
+	"time"
+)
+
+type execServer struct {
+	pb.UnimplementedExecutorServer
+	worker    worker.Worker
+	fs        filestore.FileStore
+	srcPrefix []string
+	logger    *zap.Logger
+}
+
+// New 创建 gRPC 执行器服务
+func New(worker worker.Worker, fs filestore.FileStore, srcPrefix []string, logger *zap.Logger) pb.ExecutorServer {
+	return &execServer{
+		worker:    worker,
+		fs:        fs,
+		srcPrefix: srcPrefix,
+		logger:    logger,
+	}
+}
+
+func (e *execServer) Exec(ctx context.Context, req *pb.Request) (*pb.Response, error) {
+	if len(req.GetCmd()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "no cmd provided")
+	}
+	r, err := convertPBRequest(req, e.srcPrefix)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	// 启用认证时与 http 接口相同地检查租户的限制，并以租户名称作为公平调度的队列键
+	t := tenantOf(ctx)
+	if err := t.Check(r); err != nil {
+		return nil, tenantError(err, codes.InvalidArgument)
+	}
+	if t != nil {
+		r.QueueKey = t.Name
+	}
+	if err := t.Acquire(); err != nil {
+		return nil, tenantError(err, codes.Internal)
+	}
+	e.logger.Sugar().Debugf("request: %+v", r)
+	rtCh, _ := e.worker.Submit(ctx, r)
+	rt := <-rtCh
+	t.Release(cachedFiles(rt))
+	e.logger.Sugar().Debugf("response: %+v", rt)
+	if errors.Is(rt.Error, worker.ErrQueueFull) || errors.Is(rt.Error, worker.ErrQueueTimeout) ||
+		errors.Is(rt.Error, worker.ErrDraining) || errors.Is(rt.Error, worker.ErrShutdown) ||
+		errors.Is(rt.Error, cluster.ErrNoPeer) {
+		return nil, status.Error(codes.Unavailable, rt.Error.Error())
+	}
+	if rt.Error != nil {
+		return nil, status.Error(codes.Internal, rt.Error.Error())
+	}
+	res, err := convertPBResponse(rt)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return res, nil
+}
+
+func convertPBRequest(r *pb.Request, srcPrefix []string) (*worker.Request, error) {
+	req := &worker.Request{
+		RequestID:   r.GetRequestID(),
+		Cmd:         make([]worker.Cmd, 0, len(r.GetCmd())),
+		PipeMapping: make([]worker.PipeMap, 0, len(r.GetPipeMapping())),
+	}
+	for _, c := range r.GetCmd() {
+		wc, err := convertPBCmd(c, srcPrefix)
+		if err != nil {
+			return nil, err
+		}
+		req.Cmd = append(req.Cmd, wc)
+	}
+	for _, p := range r.GetPipeMapping() {
+		req.PipeMapping = append(req.PipeMapping, convertPBPipe(p))
+	}
+	return req, nil
+}
+
+func convertPBPipe(p *pb.Request_PipeMap) worker.PipeMap {
+	return worker.PipeMap{
+		In: worker.PipeIndex{
+			Index: int(p.GetIn().GetIndex()),
+			Fd:    int(p.GetIn().GetFd()),
+		},
+		Out: worker.PipeIndex{
+			Index: int(p.GetOut().GetIndex()),
+			Fd:    int(p.GetOut().GetFd()),
+		},
+		Proxy: p.GetProxy(),
+		Name:  p.GetName(),
+		Limit: worker.Size(p.GetMax()),
+	}
+}
+
+func convertPBCmd(c *pb.Request_CmdType, srcPrefix []string) (worker.Cmd, error) {
+	w := worker.Cmd{
+		Args:              c.GetArgs(),
+		Env:               c.GetEnv(),
+		Files:             make([]worker.CmdFile, 0, len(c.GetFiles())),
+		TTY:               c.GetTty(),
+		CPULimit:          time.Duration(c.GetCpuTimeLimit()),
+		ClockLimit:        time.Duration(c.GetClockTimeLimit()),
+		MemoryLimit:       envexec.Size(c.GetMemoryLimit()),
+		StackLimit:        envexec.Size(c.GetStackLimit()),
+		ProcLimit:         c.GetProcLimit(),
+		CPURateLimit:      c.GetCpuRateLimit(),
+		CPUSetLimit:       c.GetCpuSetLimit(),
+		StrictMemoryLimit: c.GetStrictMemoryLimit(),
+		CopyOut:           convertPBCopyOut(c.GetCopyOut()),
+		CopyOutCached:     convertPBCopyOut(c.GetCopyOutCached()),
+		CopyOutMax:        c.GetCopyOutMax(),
+		CopyOutDir:        c.GetCopyOutDir(),
+	}
+	for _, f := range c.GetFiles() {
+		cf, err := convertPBFile(f, srcPrefix)
+		if err != nil {
+			return w, err
+		}
+		w.Files = append(w.Files, cf)
+	}
+	if copyIn := c.GetCopyIn(); copyIn != nil {
+		w.CopyIn = make(map[string]worker.CmdFile, len(copyIn))
+		for k, f := range copyIn {
+			cf, err := convertPBFile(f, srcPrefix)
+			if err != nil {
+				return w, err
+			}
+			w.CopyIn[k] = cf
+		}
+	}
+	w.Symlinks = c.GetSymlinks()
+	return w, nil
+}
+
+func convertPBCopyOut(copyOut []*pb.Request_CmdCopyOutFile) []worker.CmdCopyOutFile {
+	rt := make([]worker.CmdCopyOutFile, 0, len(copyOut))
+	for _, n := range copyOut {
+		rt = append(rt, worker.CmdCopyOutFile{
+			Name:     n.GetName(),
+			Optional: n.GetOptional(),
+		})
+	}
+	return rt
+}
+
+func convertPBFile(c *pb.Request_File, srcPrefix []string) (worker.CmdFile, error) {
+	switch c := c.GetFile().(type) {
+	case nil:
+		return nil, nil
+	case *pb.Request_File_Local:
+		if len(srcPrefix) != 0 {
+			ok, err := model.CheckPathPrefixes(c.Local.GetSrc(), srcPrefix)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				return nil, fmt.Errorf("file (%s) does not under (%s)", c.Local.GetSrc(), srcPrefix)
+			}
+		}
+		return &worker.LocalFile{Src: c.Local.GetSrc()}, nil
+	case *pb.Request_File_Memory:
+		return &worker.MemoryFile{Content: c.Memory.GetContent()}, nil
+	case *pb.Request_File_Cached:
+		return &worker.CachedFile{FileID: c.Cached.GetFileID()}, nil
+	case *pb.Request_File_Pipe:
+		return &worker.Collector{Name: c.Pipe.GetName(), Max: envexec.Size(c.Pipe.GetMax()), Pipe: c.Pipe.GetPipe()}, nil
+	}
+	return nil, fmt.Errorf("request file type not supported: %T", c.GetFile())
+}
+
+func convertPBResponse(r worker.Response) (ret *pb.Response, err error) {
+	// 在错误情况下，释放所有资源
+	defer func() {
+		if err != nil {
+			for _, r := range r.Results {
+				for _, f := range r.Files {
+					f.Close()
+					os.Remove(f.Name())
+				}
+			}
+		}
+	}()
+
+	ret = &pb.Response{
+		RequestID: r.RequestID,
+		Results:   make([]*pb.Response_Result, 0, len(r.Results)),
+	}
+	for _, c := range r.Results {
+		res, err := convertPBResult(c)
+		if err != nil {
+			return nil, err
+		}
+		ret.Results = append(ret.Results, res)
+	}
+	if r.Error != nil {
+		ret.Error = r.Error.Error()
+	}
+	return ret, nil
+}
+
+func convertPBResult(r worker.Result) (*pb.Response_Result, error) {
+	res := &pb.Response_Result{
+		Status:     pb.Response_Result_StatusType(r.Status),
+		ExitStatus: int32(r.ExitStatus),
+		Error:      r.Error,
+		Message:    r.Message,
+		Score:      r.Score,
+		Cached:     r.Cached,
+		Retry:      uint32(r.Retry),
+		Time:       uint64(r.Time),
+		RunTime:    uint64(r.RunTime),
+		Memory:     uint64(r.Memory),
+		FileIDs:    r.FileIDs,
+		FileError:  convertPBFileError(r.FileError),
+	}
+	if r.Files != nil {
+		res.Files = make(map[string][]byte, len(r.Files))
+		for k, f := range r.Files {
+			b, err := readAndRemove(f)
+			if err != nil {
+				return nil, err
+			}
+			res.Files[k] = b
+		}
+	}
+	return res, nil
+}
+
+func convertPBFileError(fe []envexec.FileError) []*pb.Response_FileError {
+	rt := make([]*pb.Response_FileError, 0, len(fe))
+	for _, e := range fe {
+		rt = append(rt, &pb.Response_FileError{
+			Name:    e.Name,
+			Type:    pb.Response_FileError_ErrorType(e.Type),
+			Message: e.Message,
+		})
+	}
+	return rt
+}
+
+// readAndRemove 读取收集到的文件内容并移除临时文件
+func readAndRemove(f *os.File) ([]byte, error) {
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(f)
+}
@@ -0,0 +1,405 @@
+package job
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/model"
+	"github.com/lxhcaicai/loj-judge/worker"
+	"sort"
+	"sync"
+	"time"
+)
+
+const idLength = 10
+
+// State 定义异步任务的状态
+type State string
+
+const (
+	StateQueued   State = "queued"
+	StateRunning  State = "running"
+	StateFinished State = "finished"
+)
+
+var (
+	// ErrExists 表示相同 id 的任务已经存在
+	ErrExists = errors.New("job with the same id already exists")
+)
+
+// Status 定义异步任务的查询结果
+type Status struct {
+	ID         string          `json:"id"`
+	Owner      string          `json:"owner,omitempty"` // 提交任务的租户
+	State      State           `json:"state"`
+	Cancelled  bool            `json:"cancelled,omitempty"`
+	SubmitTime time.Time       `json:"submitTime"`
+	FinishTime *time.Time      `json:"finishTime,omitempty"`
+	Response   *model.Response `json:"response,omitempty"`
+}
+
+type job struct {
+	Status
+	req    *model.Request  // 完成后被清除
+	wreq   *worker.Request // 完成后被清除
+	done   func(model.Response)
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Config 定义异步任务存储的配置
+type Config struct {
+	Worker worker.Worker
+
+	// TTL 定义已完成任务结果的保存时间，CheckInterval 定义清理的间隔
+	TTL           time.Duration
+	CheckInterval time.Duration
+
+	// Dir 非空时在该目录下记录任务日志，重启后恢复未完成的任务以及已完成任务的结果
+	Dir string
+
+	// Convert 将恢复的请求以及提交任务的租户转换为 worker 请求，
+	// 返回的 done 非空时在任务完成后以结果调用
+	Convert func(req *model.Request, owner string) (r *worker.Request, done func(model.Response), err error)
+
+	// OnFinish 在任务完成后调用 (可以为空)
+	OnFinish func(*model.Request, model.Response)
+}
+
+// Store 保存异步任务以及已完成任务的结果，结果在 ttl 后被清除。
+// 任务作为持久化的请求提交，不受 worker 等待队列长度的限制
+type Store struct {
+	worker   worker.Worker
+	ttl      time.Duration
+	convert  func(*model.Request, string) (*worker.Request, func(model.Response), error)
+	onFinish func(*model.Request, model.Response)
+	log      *jobLog
+	done     chan struct{}
+	once     sync.Once
+
+	mu     sync.Mutex
+	jobs   map[string]*job
+	closed bool
+}
+
+// NewStore 创建异步任务存储，恢复日志中的任务，并定期清理超过 ttl 的已完成任务。
+// 恢复的任务立即提交，因此 worker 需要已经启动
+func NewStore(conf Config) (*Store, error) {
+	s := &Store{
+		worker:   conf.Worker,
+		ttl:      conf.TTL,
+		convert:  conf.Convert,
+		onFinish: conf.OnFinish,
+		jobs:     make(map[string]*job),
+		done:     make(chan struct{}),
+	}
+	if conf.Dir != "" {
+		l, recs, err := openJobLog(conf.Dir)
+		if err != nil {
+			return nil, err
+		}
+		s.log = l
+		s.replay(recs)
+	}
+	go s.checkTimeoutLoop(conf.CheckInterval)
+	return s, nil
+}
+
+// Submit 提交任务并立即返回任务 id，id 为空时自动生成。
+// req 为 r 转换前的请求，启用日志时被记录以便重启后恢复。
+// owner 为提交任务的租户，done 非空时在任务完成后以结果调用 (例如释放租户的名额)，提交失败时不调用
+func (s *Store) Submit(req *model.Request, r *worker.Request, owner string, done func(model.Response)) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := req.RequestID
+	if id == "" {
+		var err error
+		if id, err = s.generateID(); err != nil {
+			return "", err
+		}
+	} else if _, ok := s.jobs[id]; ok {
+		return "", ErrExists
+	}
+	if s.worker.QueueStats().Draining {
+		return "", worker.ErrDraining
+	}
+	now := time.Now()
+	if s.log != nil {
+		if err := s.log.append(record{Op: opSubmit, ID: id, Time: now, Owner: owner, Request: req}, true); err != nil {
+			return "", err
+		}
+	}
+	j := s.newJob(id, now, req, r)
+	j.Owner = owner
+	j.done = done
+	s.start(j)
+	return id, nil
+}
+
+func (s *Store) newJob(id string, submitTime time.Time, req *model.Request, r *worker.Request) *job {
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &job{
+		Status: Status{
+			ID:         id,
+			State:      StateQueued,
+			SubmitTime: submitTime,
+		},
+		req:    req,
+		wreq:   r,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	s.jobs[id] = j
+	return j
+}
+
+func (s *Store) start(j *job) {
+	r := *j.wreq
+	r.Persistent = true
+	rtCh, started := s.worker.Submit(j.ctx, &r)
+	go s.wait(j, rtCh, started)
+}
+
+func (s *Store) wait(j *job, rtCh <-chan worker.Response, started <-chan struct{}) {
+	<-started
+	s.mu.Lock()
+	if j.State == StateQueued {
+		j.State = StateRunning
+	}
+	s.mu.Unlock()
+
+	rt := <-rtCh
+	if errors.Is(rt.Error, worker.ErrShutdown) && s.log != nil {
+		// 停机时未执行的任务保留在日志中，重启后恢复
+		if j.done != nil {
+			j.done(model.Response{RequestID: rt.RequestID, ErrorMsg: rt.Error.Error()})
+		}
+		return
+	}
+	res, err := model.ConvertResponse(rt, false)
+	if err != nil {
+		res = model.Response{RequestID: rt.RequestID, ErrorMsg: err.Error()}
+	}
+	now := time.Now()
+
+	s.mu.Lock()
+	j.State = StateFinished
+	j.FinishTime = &now
+	j.Response = &res
+	j.cancel()
+	req, done := j.req, j.done
+	j.req, j.wreq, j.done = nil, nil, nil
+	if s.log != nil && !s.closed {
+		st := j.Status
+		s.log.append(record{Op: opFinish, ID: j.ID, Time: now, Status: &st}, false)
+	}
+	s.mu.Unlock()
+
+	if done != nil {
+		done(res)
+	}
+	if s.onFinish != nil {
+		s.onFinish(req, res)
+	}
+}
+
+// replay 恢复日志中的任务，未完成的任务按照提交顺序重新提交
+func (s *Store) replay(recs []record) {
+	var order []*job
+	for _, rec := range recs {
+		switch rec.Op {
+		case opSubmit:
+			if rec.Request != nil {
+				j := s.newJob(rec.ID, rec.Time, rec.Request, nil)
+				j.Owner = rec.Owner
+				order = append(order, j)
+			}
+		case opCancel:
+			if j, ok := s.jobs[rec.ID]; ok {
+				j.Cancelled = true
+				j.cancel()
+			}
+		case opFinish:
+			if rec.Status == nil {
+				continue
+			}
+			j, ok := s.jobs[rec.ID]
+			if !ok {
+				j = s.newJob(rec.ID, rec.Status.SubmitTime, nil, nil)
+				order = append(order, j)
+			}
+			j.Status = *rec.Status
+			j.req = nil
+			j.cancel()
+		}
+	}
+
+	now := time.Now()
+	var pending []*job
+	for _, j := range order {
+		if j.State == StateFinished {
+			if j.FinishTime != nil && j.FinishTime.Add(s.ttl).Before(now) {
+				delete(s.jobs, j.ID)
+			}
+			continue
+		}
+		// 取消的任务以及无法转换的请求直接完成
+		msg := "cancelled before execute"
+		if !j.Cancelled {
+			r, done, err := s.convert(j.req, j.Owner)
+			if err == nil {
+				j.State = StateQueued
+				j.wreq = r
+				j.done = done
+				pending = append(pending, j)
+				continue
+			}
+			msg = err.Error()
+		}
+		j.Response = &model.Response{RequestID: j.req.RequestID, ErrorMsg: msg}
+		j.State = StateFinished
+		j.FinishTime = &now
+		j.req = nil
+	}
+	s.compact()
+	for _, j := range pending {
+		s.start(j)
+	}
+}
+
+// compact 以存活的任务重写日志，调用时需要持有锁
+func (s *Store) compact() {
+	var finished, unfinished []*job
+	for _, j := range s.jobs {
+		if j.State == StateFinished {
+			finished = append(finished, j)
+		} else {
+			unfinished = append(unfinished, j)
+		}
+	}
+	// 未完成的任务按照提交顺序写入，以便恢复时按照原来的顺序提交
+	sort.Slice(unfinished, func(a, b int) bool {
+		return unfinished[a].SubmitTime.Before(unfinished[b].SubmitTime)
+	})
+
+	recs := make([]record, 0, len(s.jobs))
+	for _, j := range finished {
+		st := j.Status
+		recs = append(recs, record{Op: opFinish, ID: j.ID, Time: *j.FinishTime, Status: &st})
+	}
+	for _, j := range unfinished {
+		recs = append(recs, record{Op: opSubmit, ID: j.ID, Time: j.SubmitTime, Owner: j.Owner, Request: j.req})
+		if j.Cancelled {
+			recs = append(recs, record{Op: opCancel, ID: j.ID, Time: j.SubmitTime})
+		}
+	}
+	s.log.rewrite(recs)
+}
+
+// Close 停止清理过期的任务并关闭日志，未完成的任务在重启后恢复
+func (s *Store) Close() error {
+	s.once.Do(func() {
+		close(s.done)
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	if s.log != nil {
+		return s.log.close()
+	}
+	return nil
+}
+
+// Get 返回任务当前状态
+func (s *Store) Get(id string) (Status, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, ok := s.jobs[id]
+	if !ok {
+		return Status{}, false
+	}
+	return j.Status, true
+}
+
+// Cancel 取消排队中或者执行中的任务，已完成的任务不受影响
+func (s *Store) Cancel(id string) (Status, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, ok := s.jobs[id]
+	if !ok {
+		return Status{}, false
+	}
+	if j.State != StateFinished && !j.Cancelled {
+		j.Cancelled = true
+		j.cancel()
+		if s.log != nil && !s.closed {
+			s.log.append(record{Op: opCancel, ID: id, Time: time.Now()}, false)
+		}
+	}
+	return j.Status, true
+}
+
+// QueueLen 返回排队中的任务数量
+func (s *Store) QueueLen() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for _, j := range s.jobs {
+		if j.State == StateQueued {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *Store) checkTimeoutLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.checkTimeoutAndRemove()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *Store) checkTimeoutAndRemove() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for id, j := range s.jobs {
+		if j.FinishTime != nil && j.FinishTime.Add(s.ttl).Before(now) {
+			delete(s.jobs, id)
+		}
+	}
+	if s.log != nil && !s.closed && s.log.count > 2*len(s.jobs)+logCompactThreshold {
+		s.compact()
+	}
+}
+
+func (s *Store) generateID() (string, error) {
+	for range [50]struct{}{} {
+		b := make([]byte, idLength)
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		id := base32.StdEncoding.EncodeToString(b)
+		if _, ok := s.jobs[id]; !ok {
+			return id, nil
+		}
+	}
+	return "", errors.New("unique job id does not exists after tried 50 times")
+}
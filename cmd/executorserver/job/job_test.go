@@ -0,0 +1,223 @@
+package job
+
+import (
+	"context"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/model"
+	"github.com/lxhcaicai/loj-judge/envexec"
+	"github.com/lxhcaicai/loj-judge/worker"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeWorker 立即完成请求，hold 中的请求在 Shutdown 之前不返回
+type fakeWorker struct {
+	hold     map[string]bool
+	shutdown chan struct{}
+
+	mu        sync.Mutex
+	submitted []string
+}
+
+func newFakeWorker(hold ...string) *fakeWorker {
+	w := &fakeWorker{hold: make(map[string]bool), shutdown: make(chan struct{})}
+	for _, id := range hold {
+		w.hold[id] = true
+	}
+	return w
+}
+
+func (w *fakeWorker) Submit(ctx context.Context, r *worker.Request) (<-chan worker.Response, <-chan struct{}) {
+	w.mu.Lock()
+	w.submitted = append(w.submitted, r.RequestID)
+	w.mu.Unlock()
+
+	ch := make(chan worker.Response, 1)
+	started := make(chan struct{})
+	close(started)
+	go func() {
+		if w.hold[r.RequestID] {
+			<-w.shutdown
+			ch <- worker.Response{RequestID: r.RequestID, Error: worker.ErrShutdown}
+			return
+		}
+		ch <- worker.Response{RequestID: r.RequestID, Results: []worker.Result{{Status: envexec.StatusAccepted}}}
+	}()
+	return ch, started
+}
+
+func (w *fakeWorker) getSubmitted() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]string(nil), w.submitted...)
+}
+
+func (w *fakeWorker) Start() {}
+
+func (w *fakeWorker) Execute(ctx context.Context, r *worker.Request) <-chan worker.Response {
+	ch, _ := w.Submit(ctx, r)
+	return ch
+}
+
+func (w *fakeWorker) QueueStats() worker.QueueStats { return worker.QueueStats{} }
+
+func (w *fakeWorker) SetParallelism(int) {}
+
+func (w *fakeWorker) Pause() {}
+
+func (w *fakeWorker) Resume() {}
+
+func (w *fakeWorker) Drain() <-chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}
+
+func (w *fakeWorker) Shutdown() { close(w.shutdown) }
+
+func newTestStore(t *testing.T, w worker.Worker, dir string) *Store {
+	t.Helper()
+	s, err := NewStore(Config{
+		Worker:        w,
+		TTL:           time.Hour,
+		CheckInterval: time.Hour,
+		Dir:           dir,
+		Convert: func(r *model.Request, owner string) (*worker.Request, func(model.Response), error) {
+			wr, err := model.ConvertRequest(r, nil)
+			return wr, nil, err
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func submit(t *testing.T, s *Store, id, owner string) {
+	t.Helper()
+	req := &model.Request{RequestID: id, Cmd: []model.Cmd{{Args: []string{"true"}}}}
+	r, err := model.ConvertRequest(req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Submit(req, r, owner, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func waitFinished(t *testing.T, s *Store, id string) Status {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if st, ok := s.Get(id); ok && st.State == StateFinished {
+			return st
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	st, _ := s.Get(id)
+	t.Fatalf("job %s not finished: %+v", id, st)
+	return Status{}
+}
+
+func TestStoreReplay(t *testing.T) {
+	dir := t.TempDir()
+
+	w1 := newFakeWorker("pending", "cancelled")
+	s1 := newTestStore(t, w1, dir)
+	submit(t, s1, "done", "a")
+	submit(t, s1, "pending", "b")
+	submit(t, s1, "cancelled", "")
+	done := waitFinished(t, s1, "done")
+	if _, ok := s1.Cancel("cancelled"); !ok {
+		t.Fatal("expected job cancelled")
+	}
+	// 停机时未完成的任务保留在日志中
+	if err := s1.Close(); err != nil {
+		t.Fatal(err)
+	}
+	w1.Shutdown()
+
+	w2 := newFakeWorker()
+	s2 := newTestStore(t, w2, dir)
+	defer s2.Close()
+
+	// 已完成任务的结果被恢复而不重新执行
+	st, ok := s2.Get("done")
+	if !ok || st.State != StateFinished || st.Owner != "a" || st.Response == nil || st.Response.RequestID != "done" {
+		t.Fatalf("expected finished job restored, got %+v", st)
+	}
+	if !st.FinishTime.Equal(*done.FinishTime) {
+		t.Fatalf("expected finish time %v, got %v", done.FinishTime, st.FinishTime)
+	}
+
+	// 未完成的任务重新提交，取消的任务直接完成
+	st = waitFinished(t, s2, "pending")
+	if st.Owner != "b" || st.Response == nil || st.Response.ErrorMsg != "" || len(st.Response.Results) != 1 {
+		t.Fatalf("expected pending job executed after restart, got %+v", st.Response)
+	}
+	st = waitFinished(t, s2, "cancelled")
+	if !st.Cancelled || st.Response == nil || st.Response.ErrorMsg == "" {
+		t.Fatalf("expected cancelled job finished without execution, got %+v", st)
+	}
+	if got := w2.getSubmitted(); len(got) != 1 || got[0] != "pending" {
+		t.Fatalf("expected only the pending job resubmitted, got %v", got)
+	}
+
+	// 恢复后的日志在再次重启时仍然有效
+	if err := s2.Close(); err != nil {
+		t.Fatal(err)
+	}
+	s3 := newTestStore(t, newFakeWorker(), dir)
+	defer s3.Close()
+	for _, id := range []string{"done", "pending", "cancelled"} {
+		if st, ok := s3.Get(id); !ok || st.State != StateFinished {
+			t.Errorf("job %s: expected finished after second restart, got %+v", id, st)
+		}
+	}
+}
+
+func TestStoreCorruptLog(t *testing.T) {
+	dir := t.TempDir()
+	s := newTestStore(t, newFakeWorker(), dir)
+	submit(t, s, "a", "")
+	submit(t, s, "b", "")
+	waitFinished(t, s, "a")
+	waitFinished(t, s, "b")
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(dir, logFileName)
+	if fi, err := os.Stat(p); err != nil || fi.Mode().Perm() != 0600 {
+		t.Fatalf("expected log only accessible by the owner, got %v %v", fi.Mode(), err)
+	}
+	b, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 未写完的最后一条记录被忽略
+	if err := os.WriteFile(p, append(append([]byte(nil), b...), `{"op":"sub`...), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s = newTestStore(t, newFakeWorker(), dir)
+	s.Close()
+	for _, id := range []string{"a", "b"} {
+		if _, ok := s.Get(id); !ok {
+			t.Fatalf("expected job %s restored after a truncated record", id)
+		}
+	}
+
+	// 中间损坏的记录使打开失败，并且不修改日志
+	corrupt := append([]byte("not json\n"), b...)
+	if err := os.WriteFile(p, corrupt, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewStore(Config{Worker: newFakeWorker(), TTL: time.Hour, CheckInterval: time.Hour, Dir: dir}); err == nil {
+		t.Fatal("expected error for a corrupt record")
+	}
+	if got, _ := os.ReadFile(p); string(got) != string(corrupt) {
+		t.Fatal("expected corrupt log left unchanged")
+	}
+}
@@ -0,0 +1,159 @@
+package job
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/model"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	logFileName = "jobs.log"
+
+	// 日志中的记录数量超过存活记录的两倍并且超过该数量时压缩日志
+	logCompactThreshold = 1024
+)
+
+// 日志记录的类型
+const (
+	opSubmit = "submit"
+	opCancel = "cancel"
+	opFinish = "finish"
+)
+
+// record 定义日志中的一条记录，每行一条 json
+type record struct {
+	Op      string         `json:"op"`
+	ID      string         `json:"id"`
+	Time    time.Time      `json:"time"`
+	Owner   string         `json:"owner,omitempty"`   // submit
+	Request *model.Request `json:"request,omitempty"` // submit
+	Status  *Status        `json:"status,omitempty"`  // finish
+}
+
+// jobLog 是只追加的任务日志，记录提交、取消以及完成的任务，重启时用于恢复
+type jobLog struct {
+	path  string
+	f     *os.File
+	count int // 日志中的记录数量
+}
+
+// openJobLog 打开目录下的任务日志并读取其中的记录。
+// 崩溃时未写完的最后一条记录被忽略，其他无法解析的记录返回错误并且不修改日志。
+// 日志中包含提交的代码以及数据，只有当前用户可以读写
+func openJobLog(dir string) (*jobLog, []record, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, nil, err
+	}
+	p := filepath.Join(dir, logFileName)
+
+	var recs []record
+	f, err := os.Open(p)
+	switch {
+	case err == nil:
+		recs, err = readRecords(f)
+		f.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", p, err)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, nil, err
+	}
+
+	l := &jobLog{path: p}
+	if err := l.rewrite(recs); err != nil {
+		return nil, nil, err
+	}
+	return l, recs, nil
+}
+
+// readRecords 按行读取记录，没有换行结尾的最后一行为未写完的记录
+func readRecords(r io.Reader) ([]record, error) {
+	var recs []record
+	br := bufio.NewReader(r)
+	for n := 1; ; n++ {
+		line, err := br.ReadBytes('\n')
+		if err == io.EOF {
+			return recs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var rec record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		recs = append(recs, rec)
+	}
+}
+
+// append 写入一条记录，sync 为真时确保记录已经写入磁盘
+func (l *jobLog) append(rec record, sync bool) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := l.f.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	l.count++
+	if sync {
+		return l.f.Sync()
+	}
+	return nil
+}
+
+// rewrite 以给定的记录替换日志内容
+func (l *jobLog) rewrite(recs []record) error {
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), logFileName+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	e := json.NewEncoder(w)
+	for _, rec := range recs {
+		if err := e.Encode(rec); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), l.path); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if l.f != nil {
+		l.f.Close()
+	}
+	l.f = f
+	l.count = len(recs)
+	return nil
+}
+
+func (l *jobLog) close() error {
+	return l.f.Close()
+}
@@ -0,0 +1,288 @@
+package language
+
+import (
+	"fmt"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/model"
+	"github.com/lxhcaicai/loj-judge/envexec"
+	"github.com/lxhcaicai/loj-judge/worker"
+	"gopkg.in/yaml.v2"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 模板中可以使用的占位符
+const (
+	SourcePlaceholder     = "{source}"
+	ExecutablePlaceholder = "{executable}"
+)
+
+// 语言模板的阶段
+const (
+	StageCompile = "compile"
+	StageRun     = "run"
+)
+
+// Size 在 yaml 中可以使用 256m 等形式表示的字节数
+type Size uint64
+
+// UnmarshalYAML 解析数字或者带单位的大小
+func (s *Size) UnmarshalYAML(unmarshal func(any) error) error {
+	var str string
+	if err := unmarshal(&str); err != nil {
+		return err
+	}
+	var v envexec.Size
+	if err := v.Set(str); err != nil {
+		return err
+	}
+	*s = Size(v)
+	return nil
+}
+
+// Template 定义编译或者运行命令的模板以及默认限制
+type Template struct {
+	Args        []string      `yaml:"args" json:"args"`
+	Env         []string      `yaml:"env" json:"env,omitempty"`
+	CPULimit    time.Duration `yaml:"cpuLimit" json:"cpuLimit,omitempty"`
+	ClockLimit  time.Duration `yaml:"clockLimit" json:"clockLimit,omitempty"`
+	MemoryLimit Size          `yaml:"memoryLimit" json:"memoryLimit,omitempty"`
+	StackLimit  Size          `yaml:"stackLimit" json:"stackLimit,omitempty"`
+	ProcLimit   uint64        `yaml:"procLimit" json:"procLimit,omitempty"`
+}
+
+// Language 定义一种语言的编译与运行方式
+type Language struct {
+	Name           string    `yaml:"name" json:"name"`
+	DisplayName    string    `yaml:"displayName" json:"displayName,omitempty"`
+	SourceName     string    `yaml:"sourceName" json:"sourceName"`
+	ExecutableName string    `yaml:"executableName" json:"executableName"`
+	Compile        *Template `yaml:"compile" json:"compile,omitempty"`
+	Run            *Template `yaml:"run" json:"run"`
+
+	// 运行阶段额外允许的内存以及时间限制的倍数 (例如 JVM)，不计入租户的限制
+	ExtraMemory Size    `yaml:"extraMemory" json:"extraMemory,omitempty"`
+	TimeFactor  float64 `yaml:"timeFactor" json:"timeFactor,omitempty"`
+}
+
+type languages struct {
+	Languages []Language `yaml:"languages"`
+}
+
+// Catalog 保存服务端预设的语言
+type Catalog struct {
+	languages map[string]*Language
+}
+
+// ReadCatalog 从 yaml 文件读取语言预设
+func ReadCatalog(p string) (*Catalog, error) {
+	d, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	var l languages
+	if err := yaml.UnmarshalStrict(d, &l); err != nil {
+		return nil, err
+	}
+	return NewCatalog(l.Languages)
+}
+
+// NewCatalog 创建语言预设，语言名称不能重复
+func NewCatalog(langs []Language) (*Catalog, error) {
+	c := &Catalog{languages: make(map[string]*Language, len(langs))}
+	for i := range langs {
+		l := langs[i]
+		if l.Name == "" {
+			return nil, fmt.Errorf("language #%d: name not provided", i)
+		}
+		if _, ok := c.languages[l.Name]; ok {
+			return nil, fmt.Errorf("language %s: defined more than once", l.Name)
+		}
+		if l.Run == nil || len(l.Run.Args) == 0 {
+			return nil, fmt.Errorf("language %s: run args not provided", l.Name)
+		}
+		if l.TimeFactor == 0 {
+			l.TimeFactor = 1
+		}
+		if l.TimeFactor < 0 {
+			return nil, fmt.Errorf("language %s: invalid time factor %v", l.Name, l.TimeFactor)
+		}
+		c.languages[l.Name] = &l
+	}
+	return c, nil
+}
+
+// List 按名称顺序返回所有语言
+func (c *Catalog) List() []Language {
+	rt := make([]Language, 0, len(c.languages))
+	for _, l := range c.languages {
+		rt = append(rt, *l)
+	}
+	sort.Slice(rt, func(i, j int) bool {
+		return rt[i].Name < rt[j].Name
+	})
+	return rt
+}
+
+// Padding 记录运行阶段的命令需要增加的内存以及时间限制的倍数，
+// 在检查租户的限制之后再应用到 worker 请求
+type Padding []pad
+
+type pad struct {
+	stage       int // 命令所在的阶段，-1 表示请求的命令
+	cmd         int
+	checker     bool
+	extraMemory uint64
+	timeFactor  float64
+}
+
+// Apply 将请求中引用语言的命令展开为完整的命令，返回运行阶段的额外限制
+func (c *Catalog) Apply(r *model.Request) (Padding, error) {
+	var p Padding
+	for i := range r.Cmd {
+		if err := c.applyCmd(&r.Cmd[i], pad{stage: -1, cmd: i}, &p); err != nil {
+			return nil, err
+		}
+	}
+	for j, s := range r.Stages {
+		for i := range s.Cmd {
+			if err := c.applyCmd(&s.Cmd[i], pad{stage: j, cmd: i}, &p); err != nil {
+				return nil, fmt.Errorf("stage %s: %w", s.Name, err)
+			}
+		}
+	}
+	return p, nil
+}
+
+// Pad 将额外的内存以及时间限制的倍数应用到由展开后的请求转换得到的 worker 请求
+func (p Padding) Pad(r *worker.Request) {
+	for _, d := range p {
+		cmds := r.Cmd
+		if d.stage >= 0 {
+			cmds = r.Stages[d.stage].Cmd
+		}
+		c := &cmds[d.cmd]
+		if d.checker {
+			c = &c.Checker.Cmd
+		}
+		c.CPULimit = scale(c.CPULimit, d.timeFactor)
+		c.ClockLimit = scale(c.ClockLimit, d.timeFactor)
+		if c.MemoryLimit > 0 {
+			c.MemoryLimit += worker.Size(d.extraMemory)
+		}
+	}
+}
+
+// Used 返回请求中引用的语言名称，需要在 Apply 之前调用
+func Used(r *model.Request) []string {
+	seen := make(map[string]bool)
+	var rt []string
+	var add func(cmd *model.Cmd)
+	add = func(cmd *model.Cmd) {
+		if cmd.Checker != nil {
+			add(&cmd.Checker.Cmd)
+		}
+		if cmd.Language != nil && !seen[cmd.Language.Name] {
+			seen[cmd.Language.Name] = true
+			rt = append(rt, cmd.Language.Name)
+		}
+	}
+	for i := range r.Cmd {
+		add(&r.Cmd[i])
+	}
+	for _, s := range r.Stages {
+		for i := range s.Cmd {
+			add(&s.Cmd[i])
+		}
+	}
+	return rt
+}
+
+func (c *Catalog) applyCmd(cmd *model.Cmd, at pad, p *Padding) error {
+	if cmd.Checker != nil {
+		checker := at
+		checker.checker = true
+		if err := c.applyCmd(&cmd.Checker.Cmd, checker, p); err != nil {
+			return fmt.Errorf("checker: %w", err)
+		}
+	}
+	ref := cmd.Language
+	if ref == nil {
+		return nil
+	}
+	l, ok := c.languages[ref.Name]
+	if !ok {
+		return fmt.Errorf("language %s: not found", ref.Name)
+	}
+
+	var t *Template
+	switch ref.Stage {
+	case StageCompile:
+		t = l.Compile
+	case "", StageRun:
+		t = l.Run
+	default:
+		return fmt.Errorf("language %s: unknown stage %s", ref.Name, ref.Stage)
+	}
+	if t == nil {
+		return fmt.Errorf("language %s: stage %s not supported", ref.Name, ref.Stage)
+	}
+
+	source, executable := l.SourceName, l.ExecutableName
+	if ref.SourceName != "" {
+		source = ref.SourceName
+	}
+	if ref.ExecutableName != "" {
+		executable = ref.ExecutableName
+	}
+	rp := strings.NewReplacer(SourcePlaceholder, source, ExecutablePlaceholder, executable)
+	expand := func(s []string) []string {
+		rt := make([]string, 0, len(s))
+		for _, v := range s {
+			rt = append(rt, rp.Replace(v))
+		}
+		return rt
+	}
+
+	cmd.Args = append(expand(t.Args), expand(cmd.Args)...)
+	cmd.Env = append(expand(t.Env), expand(cmd.Env)...)
+	cmd.CopyOut = expand(cmd.CopyOut)
+	cmd.CopyOutCached = expand(cmd.CopyOutCached)
+	if cmd.CopyIn != nil {
+		copyIn := make(map[string]model.CmdFile, len(cmd.CopyIn))
+		for k, v := range cmd.CopyIn {
+			copyIn[rp.Replace(k)] = v
+		}
+		cmd.CopyIn = copyIn
+	}
+
+	// 未指定的限制使用语言的默认值
+	setDefault(&cmd.CPULimit, uint64(t.CPULimit))
+	setDefault(&cmd.ClockLimit, uint64(t.ClockLimit))
+	setDefault(&cmd.MemoryLimit, uint64(t.MemoryLimit))
+	setDefault(&cmd.StackLimit, uint64(t.StackLimit))
+	setDefault(&cmd.ProcLimit, t.ProcLimit)
+
+	if t == l.Run && (l.TimeFactor != 1 || l.ExtraMemory > 0) {
+		at.extraMemory, at.timeFactor = uint64(l.ExtraMemory), l.TimeFactor
+		*p = append(*p, at)
+	}
+	cmd.Language = nil
+	return nil
+}
+
+func setDefault(v *uint64, d uint64) {
+	if *v == 0 {
+		*v = d
+	}
+}
+
+func scale(v time.Duration, f float64) time.Duration {
+	s := float64(v) * f
+	if s >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(s)
+}
@@ -0,0 +1,158 @@
+package language
+
+import (
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/model"
+	"github.com/lxhcaicai/loj-judge/worker"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newTestCatalog(t *testing.T) *Catalog {
+	t.Helper()
+	c, err := NewCatalog([]Language{
+		{
+			Name:           "cpp",
+			SourceName:     "a.cc",
+			ExecutableName: "a",
+			Compile: &Template{
+				Args:        []string{"/usr/bin/g++", SourcePlaceholder, "-o", ExecutablePlaceholder},
+				Env:         []string{"PATH=/usr/bin"},
+				CPULimit:    10 * time.Second,
+				MemoryLimit: 512 << 20,
+			},
+			Run: &Template{Args: []string{ExecutablePlaceholder}, CPULimit: time.Second, MemoryLimit: 256 << 20},
+		},
+		{
+			Name:           "java",
+			SourceName:     "Main.java",
+			ExecutableName: "Main",
+			Run:            &Template{Args: []string{"/usr/bin/java", ExecutablePlaceholder}, MemoryLimit: 256 << 20},
+			ExtraMemory:    64 << 20,
+			TimeFactor:     2,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestApply(t *testing.T) {
+	c := newTestCatalog(t)
+	r := &model.Request{Cmd: []model.Cmd{{
+		Args:          []string{"-O2"},
+		CopyIn:        map[string]model.CmdFile{SourcePlaceholder: {}},
+		CopyOutCached: []string{ExecutablePlaceholder},
+		CPULimit:      uint64(5 * time.Second),
+		Language:      &model.LanguageRef{Name: "cpp", Stage: StageCompile, SourceName: "main.cc"},
+	}}}
+	p, err := c.Apply(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := r.Cmd[0]
+	if expected := []string{"/usr/bin/g++", "main.cc", "-o", "a", "-O2"}; !reflect.DeepEqual(cmd.Args, expected) {
+		t.Errorf("expected args %v, got %v", expected, cmd.Args)
+	}
+	if _, ok := cmd.CopyIn["main.cc"]; !ok || len(cmd.CopyIn) != 1 {
+		t.Errorf("expected source copied in as main.cc, got %v", cmd.CopyIn)
+	}
+	if len(cmd.CopyOutCached) != 1 || cmd.CopyOutCached[0] != "a" || len(cmd.Env) != 1 {
+		t.Errorf("unexpected expanded cmd: %+v", cmd)
+	}
+	// 请求中指定的限制优先，未指定的使用语言的默认值
+	if cmd.CPULimit != uint64(5*time.Second) || cmd.MemoryLimit != 512<<20 {
+		t.Errorf("unexpected limits: cpu %d memory %d", cmd.CPULimit, cmd.MemoryLimit)
+	}
+	if cmd.Language != nil || len(p) != 0 {
+		t.Errorf("expected language reference removed without padding, got %+v %+v", cmd.Language, p)
+	}
+
+	for _, ref := range []model.LanguageRef{
+		{Name: "go"},
+		{Name: "cpp", Stage: "link"},
+		{Name: "java", Stage: StageCompile},
+	} {
+		ref := ref
+		r := &model.Request{Cmd: []model.Cmd{{Language: &ref}}}
+		if _, err := c.Apply(r); err == nil {
+			t.Errorf("%+v: expected error", ref)
+		}
+	}
+}
+
+func TestPad(t *testing.T) {
+	c := newTestCatalog(t)
+	r := &model.Request{Stages: []model.Stage{
+		{Name: "compile", Cmd: []model.Cmd{{Language: &model.LanguageRef{Name: "cpp", Stage: StageCompile}}}},
+		{Name: "run", Cmd: []model.Cmd{
+			{
+				Language:   &model.LanguageRef{Name: "java"},
+				CPULimit:   uint64(time.Second),
+				ClockLimit: uint64(3 * time.Second),
+				Checker: &model.Checker{
+					Cmd: model.Cmd{Language: &model.LanguageRef{Name: "java"}, CPULimit: uint64(time.Second)},
+				},
+			},
+			{Language: &model.LanguageRef{Name: "cpp"}},
+		}},
+	}}
+	p, err := c.Apply(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 只有 java 的运行命令 (包括评测程序) 需要额外的限制
+	if len(p) != 2 {
+		t.Fatalf("expected 2 padded commands, got %+v", p)
+	}
+
+	wr := &worker.Request{Stages: []worker.Stage{
+		{Cmd: []worker.Cmd{{CPULimit: time.Second, MemoryLimit: 256 << 20}}},
+		{Cmd: []worker.Cmd{
+			{
+				CPULimit:    time.Second,
+				ClockLimit:  3 * time.Second,
+				MemoryLimit: 256 << 20,
+				Checker:     &worker.Checker{Cmd: worker.Cmd{CPULimit: time.Second}},
+			},
+			{CPULimit: time.Second, MemoryLimit: 256 << 20},
+		}},
+	}}
+	p.Pad(wr)
+	sol := wr.Stages[1].Cmd[0]
+	if sol.CPULimit != 2*time.Second || sol.ClockLimit != 6*time.Second || sol.MemoryLimit != (256+64)<<20 {
+		t.Errorf("unexpected padded solution limits: %+v", sol)
+	}
+	// 没有内存限制的命令不增加内存
+	if chk := sol.Checker.Cmd; chk.CPULimit != 2*time.Second || chk.MemoryLimit != 0 {
+		t.Errorf("unexpected padded checker limits: %+v", chk)
+	}
+	for _, cmd := range []worker.Cmd{wr.Stages[0].Cmd[0], wr.Stages[1].Cmd[1]} {
+		if cmd.CPULimit != time.Second || cmd.MemoryLimit != 256<<20 {
+			t.Errorf("expected command not padded, got %+v", cmd)
+		}
+	}
+}
+
+func TestUsed(t *testing.T) {
+	r := &model.Request{
+		Cmd: []model.Cmd{
+			{Language: &model.LanguageRef{Name: "cpp", Stage: StageCompile}},
+			{Args: []string{"/bin/true"}},
+		},
+		Stages: []model.Stage{{Cmd: []model.Cmd{
+			{Language: &model.LanguageRef{Name: "cpp"}},
+			{
+				Language: &model.LanguageRef{Name: "python"},
+				Checker:  &model.Checker{Cmd: model.Cmd{Language: &model.LanguageRef{Name: "java"}}},
+			},
+		}}},
+	}
+	if got, expected := Used(r), []string{"cpp", "java", "python"}; !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+	if got := Used(&model.Request{Cmd: []model.Cmd{{Args: []string{"/bin/true"}}}}); len(got) != 0 {
+		t.Fatalf("expected no language used, got %v", got)
+	}
+}
@@ -2,11 +2,53 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"net"
+	"os"
+	"os/user"
+	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 )
 
+const (
+	unixPrefix    = "unix:"
+	systemdPrefix = "systemd:"
+
+	// systemd 传递的第一个文件描述符
+	listenFdsStart = 3
+)
+
+// socketPerm 定义 unix socket 文件的权限，gid 为 -1 时不修改所属组
+type socketPerm struct {
+	mode os.FileMode
+	gid  int
+}
+
+// newSocketPerm 解析八进制的权限以及组名或者组 id
+func newSocketPerm(mode, group string) (socketPerm, error) {
+	m, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil {
+		return socketPerm{}, fmt.Errorf("invalid unix socket mode %q: %w", mode, err)
+	}
+	p := socketPerm{mode: os.FileMode(m) & os.ModePerm, gid: -1}
+	if group == "" {
+		return p, nil
+	}
+	if p.gid, err = strconv.Atoi(group); err == nil {
+		return p, nil
+	}
+	g, err := user.LookupGroup(group)
+	if err != nil {
+		return socketPerm{}, err
+	}
+	if p.gid, err = strconv.Atoi(g.Gid); err != nil {
+		return socketPerm{}, err
+	}
+	return p, nil
+}
+
 type multiListener struct {
 	listeners []*net.TCPListener
 	connChan  chan acceptResult
@@ -40,7 +82,15 @@ type acceptResult struct {
 	err  error
 }
 
-func newListener(addr string) (net.Listener, error) {
+// newListener 监听 host:port，unix:/path 形式的 unix socket，
+// 或者 systemd:name 形式的 systemd socket activation 传递的 socket
+func newListener(addr string, perm socketPerm) (net.Listener, error) {
+	if path, ok := strings.CutPrefix(addr, unixPrefix); ok {
+		return newUnixListener(path, perm)
+	}
+	if name, ok := strings.CutPrefix(addr, systemdPrefix); ok {
+		return newSystemdListener(name)
+	}
 	host, port, err := net.SplitHostPort(addr)
 	if err != nil {
 		return nil, err
@@ -125,6 +175,103 @@ func newMultiListener(ips []net.IP, port int) (lis net.Listener, err error) {
 	return rt, nil
 }
 
+// newUnixListener 监听 unix socket 并设置文件权限，之前遗留的 socket 文件被删除
+func newUnixListener(path string, perm socketPerm) (net.Listener, error) {
+	if path == "" {
+		return nil, fmt.Errorf("unix socket path not provided")
+	}
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if perm.gid >= 0 {
+		if err := os.Chown(path, -1, perm.gid); err != nil {
+			l.Close()
+			return nil, err
+		}
+	}
+	if err := os.Chmod(path, perm.mode); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+var (
+	systemdOnce  sync.Once
+	systemdFiles map[string]*os.File // 按名称以及序号索引
+	systemdErr   error
+)
+
+// newSystemdListener 返回 systemd 通过 LISTEN_FDS 传递的 socket，
+// name 为 FileDescriptorName 或者从 0 开始的序号
+func newSystemdListener(name string) (net.Listener, error) {
+	initSystemdFiles()
+	if systemdErr != nil {
+		return nil, systemdErr
+	}
+	f, ok := systemdFiles[name]
+	if !ok {
+		return nil, fmt.Errorf("socket %q not passed by systemd or already used", name)
+	}
+	for k, v := range systemdFiles {
+		if v == f {
+			delete(systemdFiles, k)
+		}
+	}
+	// FileListener 复制文件描述符 (close-on-exec)，关闭原来的文件描述符
+	l, err := net.FileListener(f)
+	f.Close()
+	return l, err
+}
+
+// initSystemdFiles 读取 systemd 传递的 socket 并设置 close-on-exec，
+// 需要在创建容器等子进程之前调用，以免 socket 被子进程继承
+func initSystemdFiles() {
+	systemdOnce.Do(loadSystemdFiles)
+}
+
+// loadSystemdFiles 读取 LISTEN_PID、LISTEN_FDS 以及 LISTEN_FDNAMES，并清除这些环境变量
+func loadSystemdFiles() {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		systemdErr = fmt.Errorf("no socket passed by systemd")
+		return
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		systemdErr = fmt.Errorf("no socket passed by systemd")
+		return
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	systemdFiles = make(map[string]*os.File, n*2)
+	for i := 0; i < n; i++ {
+		fd := listenFdsStart + i
+		syscall.CloseOnExec(fd)
+		name := strconv.Itoa(i)
+		f := os.NewFile(uintptr(fd), name)
+		systemdFiles[name] = f
+		if i < len(names) && names[i] != "" {
+			if _, ok := systemdFiles[names[i]]; !ok {
+				systemdFiles[names[i]] = f
+			}
+		}
+	}
+}
+
 func printListener(lis net.Listener) string {
 	switch l := lis.(type) {
 	case *multiListener:
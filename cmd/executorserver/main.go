@@ -3,34 +3,70 @@ package main
 import (
 	"context"
 	crypto_rand "crypto/rand"
+	"crypto/tls"
 	"encoding/binary"
 	"errors"
 	"flag"
 	"fmt"
 	ginzap "github.com/gin-contrib/zap"
 	"github.com/gin-gonic/gin"
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	grpc_zap "github.com/grpc-ecosystem/go-grpc-middleware/logging/zap"
+	grpc_recovery "github.com/grpc-ecosystem/go-grpc-middleware/recovery"
+	grpc_ctxtags "github.com/grpc-ecosystem/go-grpc-middleware/tags"
+	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/cluster"
 	"github.com/lxhcaicai/loj-judge/cmd/executorserver/config"
+	grpcexecutor "github.com/lxhcaicai/loj-judge/cmd/executorserver/grpc_executor"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/job"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/language"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/model"
 	restexecutor "github.com/lxhcaicai/loj-judge/cmd/executorserver/rest_executor"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/tenant"
 	"github.com/lxhcaicai/loj-judge/cmd/executorserver/version"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/webhook"
 	"github.com/lxhcaicai/loj-judge/env"
 	"github.com/lxhcaicai/loj-judge/env/pool"
 	"github.com/lxhcaicai/loj-judge/envexec"
 	"github.com/lxhcaicai/loj-judge/filestore"
+	"github.com/lxhcaicai/loj-judge/pb"
 	"github.com/lxhcaicai/loj-judge/worker"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
 	"log"
 	math_rand "math/rand"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 )
 
 var logger *zap.Logger
 
+const (
+	jobTimeoutCheckInterval = 15 * time.Second
+	jobLogDir               = "jobs"
+	webhookBackoff          = time.Second
+	webhookLogSize          = 256
+	tlsReloadInterval       = 10 * time.Second
+
+	// 节点心跳间隔为超时时间的三分之一，过小的值会使节点频繁失效
+	minNodeTimeout = time.Second
+
+	// 节点的健康检查间隔同时作为检查的超时时间
+	minPeerCheckInterval = time.Second
+)
+
 func main() {
 	conf := loadConf()
 	if conf.Version {
@@ -42,21 +78,63 @@ func main() {
 
 	logger.Sugar().Infof("config loaded: %+v", conf)
 	initRand()
+	initSystemdFiles()
 	warnIfNotLinux()
 
 	// Init environment pool
+	jobDir := jobLogPath(conf)
 	fs, _ := newFilesStore(conf)
+	tenants := newTenants(conf, fs)
+	if conf.CoordinatorAddr != "" {
+		fs = cluster.NewFileStore(fs, conf.CoordinatorAddr, conf.APIKey, nil)
+	}
+	fed := newFederation(conf, fs)
+	if fed != nil {
+		fs = fed.FileStore()
+	}
 	b, builderParam := newEnvBuilder(conf)
 	envPool := newEnvPool(b, conf.EnableMetrics)
 	prefork(envPool, conf.PreFork)
-	work := newWorker(conf, envPool, fs)
+	coord := newCoordinator(conf)
+	var executor func(context.Context, *worker.Request) worker.Response
+	switch {
+	case coord != nil:
+		executor = coord.Execute
+	case fed != nil:
+		executor = fed.Execute
+	}
+	work := newWorker(conf, envPool, fs, executor)
 	work.Start()
 	logger.Sugar().Infof("Started worker with parallelism=%d, workdir=%s, timeLimitCheckInterval=%v",
 		conf.Parallelism, conf.Dir, conf.TimeLimitCheckerInterval)
+	if coord != nil {
+		coord.Start(work)
+		logger.Sugar().Info("Started coordinator, parallelism follows registered nodes")
+	}
+	if fed != nil {
+		fed.Start()
+		logger.Sugar().Infof("Started federation forwarding requests to %v", conf.Peers)
+	}
+	node := newNode(conf, work, fs)
+	notifier := newNotifier(conf)
+	languages := newLanguages(conf)
+	jobs := newJobStore(conf, jobDir, work, notifier, languages, tenants)
+	tlsConf, err := newTLSConfig(conf.TLSCert, conf.TLSKey, conf.TLSClientCA, tlsReloadInterval)
+	if err != nil {
+		logger.Sugar().Fatal("load TLS config failed ", err)
+	}
+	perm, err := newSocketPerm(conf.UnixSocketMode, conf.UnixSocketGroup)
+	if err != nil {
+		logger.Sugar().Fatal("invalid unix socket permission ", err)
+	}
 	servers := []initFunc{
 		cleanUpWorker(work),
+		cleanUpNotifier(notifier),
+		cleanUpJobs(jobs),
+		cleanUpCluster(coord, node, fed),
 		//cleanUpFs(fsCleanUp),
-		initHTTPServer(conf, work, fs, builderParam),
+		initHTTPServer(conf, work, fs, jobs, notifier, languages, builderParam, coord, fed, tenants, tlsConf, perm),
+		initGRPCServer(conf, work, fs, tenants, tlsConf, perm),
 	}
 
 	// 优雅停机
@@ -77,9 +155,10 @@ func main() {
 	}
 
 	// 优雅关闭
-	signal.Notify(sig, os.Interrupt)
+	// systemd 等服务管理器使用 SIGTERM 停止服务
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
 	<-sig
-	signal.Reset(os.Interrupt)
+	signal.Reset(os.Interrupt, syscall.SIGTERM)
 
 	logger.Sugar().Info("Shutting Down...")
 	ctx, cancel := context.WithTimeout(context.TODO(), time.Second*3)
@@ -115,23 +194,30 @@ func loadConf() *config.Config {
 type stopFunc func(ctx context.Context) error
 type initFunc func() (start func(), cleanUp stopFunc)
 
-func initHTTPServer(conf *config.Config, work worker.Worker, fs filestore.FileStore, builderParam map[string]any) initFunc {
+func initHTTPServer(conf *config.Config, work worker.Worker, fs filestore.FileStore, jobs *job.Store, notifier *webhook.Notifier, languages *language.Catalog, builderParam map[string]any, coord *cluster.Coordinator, fed *cluster.Federation, tenants *tenant.Registry, tlsConf *tls.Config, perm socketPerm) initFunc {
 	return func() (start func(), cleanUp stopFunc) {
 		// Init http handle
-		r := initHTTPMux(conf, work, fs, builderParam)
+		r := initHTTPMux(conf, work, fs, jobs, notifier, languages, builderParam, coord, fed, tenants)
 		srv := http.Server{
-			Addr:    conf.HTTPAddr,
-			Handler: r,
+			Addr:      conf.HTTPAddr,
+			Handler:   r,
+			TLSConfig: tlsConf,
 		}
 
 		return func() {
-				lis, err := newListener(conf.HTTPAddr)
+				lis, err := newListener(conf.HTTPAddr, perm)
 				if err != nil {
 					logger.Sugar().Error("Http server listen failed: ", err)
 					return
 				}
-				logger.Sugar().Info("Starting http server at ", conf.HTTPAddr, " with listener ", printListener(lis))
-				if err := srv.Serve(lis); errors.Is(err, http.ErrServerClosed) {
+				if tlsConf != nil {
+					logger.Sugar().Info("Starting https server at ", conf.HTTPAddr, " with listener ", printListener(lis))
+					err = srv.ServeTLS(lis, "", "")
+				} else {
+					logger.Sugar().Info("Starting http server at ", conf.HTTPAddr, " with listener ", printListener(lis))
+					err = srv.Serve(lis)
+				}
+				if errors.Is(err, http.ErrServerClosed) {
 					logger.Sugar().Info("Http server stopped: ", err)
 				} else {
 					logger.Sugar().Error("Http server stopped: ", err)
@@ -143,7 +229,75 @@ func initHTTPServer(conf *config.Config, work worker.Worker, fs filestore.FileSt
 	}
 }
 
-func initHTTPMux(conf *config.Config, work worker.Worker, fs filestore.FileStore, builderParam map[string]any) http.Handler {
+func initGRPCServer(conf *config.Config, work worker.Worker, fs filestore.FileStore, tenants *tenant.Registry, tlsConf *tls.Config, perm socketPerm) initFunc {
+	return func() (start func(), cleanUp stopFunc) {
+		if !conf.EnableGRPC {
+			return nil, nil
+		}
+		// Init gRPC server
+		esServer := grpcexecutor.New(work, fs, conf.SrcPrefix, logger)
+		grpcServer := newGRPCServer(conf, esServer, tenants, tlsConf)
+
+		return func() {
+				lis, err := newListener(conf.GRPCAddr, perm)
+				if err != nil {
+					logger.Sugar().Error("gRPC server listen failed: ", err)
+					return
+				}
+				logger.Sugar().Info("Starting gRPC server at ", conf.GRPCAddr, " with listener ", printListener(lis))
+				logger.Sugar().Info("gRPC server stopped: ", grpcServer.Serve(lis))
+			}, func(ctx context.Context) error {
+				grpcServer.GracefulStop()
+				logger.Sugar().Info("gRPC server stopped")
+				return nil
+			}
+	}
+}
+
+func newGRPCServer(conf *config.Config, esServer pb.ExecutorServer, tenants *tenant.Registry, tlsConf *tls.Config) *grpc.Server {
+	grpc_zap.ReplaceGrpcLoggerV2(logger)
+	grpcRecovery := grpc_recovery.WithRecoveryHandler(func(p any) (err error) {
+		logger.Sugar().Error("gRPC panic: ", p)
+		return status.Errorf(codes.Internal, "panic: %v", p)
+	})
+	streamMiddleware := []grpc.StreamServerInterceptor{
+		grpc_ctxtags.StreamServerInterceptor(),
+		grpc_zap.StreamServerInterceptor(logger),
+		grpc_recovery.StreamServerInterceptor(grpcRecovery),
+	}
+	unaryMiddleware := []grpc.UnaryServerInterceptor{
+		grpc_ctxtags.UnaryServerInterceptor(),
+		grpc_zap.UnaryServerInterceptor(logger),
+		grpc_recovery.UnaryServerInterceptor(grpcRecovery),
+	}
+	// 与 http 接口相同地通过 api key 认证租户
+	if tenants != nil {
+		streamMiddleware = append(streamMiddleware, grpcexecutor.AuthStreamInterceptor(tenants))
+		unaryMiddleware = append(unaryMiddleware, grpcexecutor.AuthUnaryInterceptor(tenants))
+	}
+	if conf.EnableMetrics {
+		initGRPCMetrics()
+		streamMiddleware = append([]grpc.StreamServerInterceptor{grpc_prometheus.StreamServerInterceptor}, streamMiddleware...)
+		unaryMiddleware = append([]grpc.UnaryServerInterceptor{grpc_prometheus.UnaryServerInterceptor}, unaryMiddleware...)
+	}
+	opts := []grpc.ServerOption{
+		grpc_middleware.WithStreamServerChain(streamMiddleware...),
+		grpc_middleware.WithUnaryServerChain(unaryMiddleware...),
+		grpc.MaxRecvMsgSize(conf.GRPCMsgSize << 20),
+		grpc.MaxSendMsgSize(conf.GRPCMsgSize << 20),
+	}
+	if tlsConf != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConf)))
+	}
+	grpcServer := grpc.NewServer(opts...)
+	pb.RegisterExecutorServer(grpcServer, esServer)
+	if conf.EnableMetrics {
+		grpc_prometheus.Register(grpcServer)
+	}
+	return grpcServer
+}
+
+func initHTTPMux(conf *config.Config, work worker.Worker, fs filestore.FileStore, jobs *job.Store, notifier *webhook.Notifier, languages *language.Catalog, builderParam map[string]any, coord *cluster.Coordinator, fed *cluster.Federation, tenants *tenant.Registry) http.Handler {
 	var r *gin.Engine
 	if conf.Release {
 		gin.SetMode(gin.ReleaseMode)
@@ -152,15 +306,41 @@ func initHTTPMux(conf *config.Config, work worker.Worker, fs filestore.FileStore
 	r.Use(ginzap.Ginzap(logger, "", false))
 	r.Use(ginzap.RecoveryWithZap(logger, true))
 
+	// 启用认证时所有接口 (包括 /metrics、/version 以及 /config) 都需要认证，
+	// 未通过认证的请求不计入统计
+	if tenants != nil {
+		r.Use(restexecutor.Authenticate(tenants))
+	}
+
+	// Metrics handle
+	if conf.EnableMetrics {
+		initGinMetrics(r)
+	}
+
 	// Version handle
 	r.GET("/version", generateHandleVersion(conf))
 
 	// Config handle
 	r.GET("/config", generateHandleConfig(conf, builderParam))
 
-	restHandle := restexecutor.New(work, fs, conf.SrcPrefix, logger)
+	restHandle := restexecutor.New(work, fs, jobs, notifier, languages, conf.SrcPrefix, logger)
 	restHandle.Register(r)
 
+	// Worker admin handle
+	if conf.EnableAdmin {
+		restexecutor.NewAdmin(work).Register(r)
+	}
+
+	// Cluster handle
+	if coord != nil {
+		restexecutor.NewCluster(coord).Register(r)
+	}
+
+	// Federation handle
+	if fed != nil {
+		restexecutor.NewFederation(fed).Register(r)
+	}
+
 	return r
 }
 
@@ -240,6 +420,47 @@ func cleanUpWorker(work worker.Worker) initFunc {
 	}
 }
 
+func cleanUpCluster(coord *cluster.Coordinator, node *cluster.Node, fed *cluster.Federation) initFunc {
+	return func() (start func(), cleanUp stopFunc) {
+		return nil, func(ctx context.Context) error {
+			if fed != nil {
+				fed.Shutdown()
+				logger.Sugar().Info("Federation shutdown")
+			}
+			if coord != nil {
+				coord.Shutdown()
+				logger.Sugar().Info("Coordinator shutdown")
+			}
+			if node != nil {
+				err := node.Shutdown(ctx)
+				logger.Sugar().Info("Node shutdown")
+				return err
+			}
+			return nil
+		}
+	}
+}
+
+func cleanUpNotifier(notifier *webhook.Notifier) initFunc {
+	return func() (start func(), cleanUp stopFunc) {
+		return nil, func(ctx context.Context) error {
+			err := notifier.Shutdown(ctx)
+			logger.Sugar().Info("Webhook notifier shutdown")
+			return err
+		}
+	}
+}
+
+func cleanUpJobs(jobs *job.Store) initFunc {
+	return func() (start func(), cleanUp stopFunc) {
+		return nil, func(ctx context.Context) error {
+			err := jobs.Close()
+			logger.Sugar().Info("Job store closed")
+			return err
+		}
+	}
+}
+
 func cleanUpFs(fsCleanUp func() error) initFunc {
 	return func() (start func(), cleanUp stopFunc) {
 		if fsCleanUp() == nil {
@@ -283,7 +504,7 @@ func newFilesStore(conf *config.Config) (filestore.FileStore, func() error) {
 		logger.Sugar().Fatal("failed to MkdirAll file store dir", err)
 	}
 	fs = filestore.NewFileLocalStore(conf.Dir)
-	if conf.EnableDebug {
+	if conf.EnableMetrics {
 		fs = newMetricsFileStore(fs)
 	}
 	if conf.FileTimeout > 0 {
@@ -341,7 +562,65 @@ func prefork(envPool worker.EnvironmentPool, prefork int) {
 	}
 }
 
-func newWorker(conf *config.Config, envPool worker.EnvironmentPool, fs filestore.FileStore) worker.Worker {
+func newNotifier(conf *config.Config) *webhook.Notifier {
+	return webhook.New(webhook.Config{
+		Client:   &http.Client{Timeout: conf.WebhookTimeout},
+		MaxRetry: conf.WebhookRetry,
+		Backoff:  webhookBackoff,
+		LogSize:  webhookLogSize,
+		Logger:   logger,
+	})
+}
+
+// jobLogPath 返回持久化任务日志的目录，未启用时为空。需要在创建文件存储之前调用，
+// 未指定 -dir 时文件存储是每次启动时新建的临时目录，不能用于保存任务日志
+func jobLogPath(conf *config.Config) string {
+	switch {
+	case !conf.DurableJobs:
+		return ""
+	case conf.JobLogDir != "":
+		return conf.JobLogDir
+	case conf.Dir == "":
+		logger.Sugar().Fatal("durable jobs require -job-log-dir or -dir, the default file store dir does not survive restart")
+	}
+	return filepath.Join(conf.Dir, jobLogDir)
+}
+
+func newJobStore(conf *config.Config, dir string, work worker.Worker, notifier *webhook.Notifier, languages *language.Catalog, tenants *tenant.Registry) *job.Store {
+	s, err := job.NewStore(job.Config{
+		Worker:        work,
+		TTL:           conf.JobResultTimeout,
+		CheckInterval: jobTimeoutCheckInterval,
+		Dir:           dir,
+		Convert:       restexecutor.NewJobConvert(languages, conf.SrcPrefix, tenants),
+		OnFinish: func(r *model.Request, res model.Response) {
+			notifier.Notify(r.Callback, res)
+		},
+	})
+	if err != nil {
+		logger.Sugar().Fatal("failed to open job log ", err)
+	}
+	if dir != "" {
+		logger.Sugar().Info("Async jobs are persisted in ", dir, ", queued: ", s.QueueLen())
+	}
+	return s
+}
+
+func newLanguages(conf *config.Config) *language.Catalog {
+	c, err := language.ReadCatalog(conf.LanguageConf)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Sugar().Fatal("load language presets failed ", err)
+		}
+		logger.Sugar().Info("Languages.yaml(", conf.LanguageConf, ") does not exists, no language presets")
+		c, _ = language.NewCatalog(nil)
+		return c
+	}
+	logger.Sugar().Info("Load language presets:", conf.LanguageConf)
+	return c
+}
+
+func newWorker(conf *config.Config, envPool worker.EnvironmentPool, fs filestore.FileStore, executor func(context.Context, *worker.Request) worker.Response) worker.Worker {
 	return worker.New(worker.Config{
 		FileStore:             fs,
 		EnvironmentPool:       envPool,
@@ -353,5 +632,97 @@ func newWorker(conf *config.Config, envPool worker.EnvironmentPool, fs filestore
 		CopyOutLimit:          *conf.CopyOutLimit,
 		OpenFileLimit:         uint64(conf.OpenFileLimit),
 		ExecObserver:          execObserve,
+		ResultCacheSize:       conf.CompileCacheSize,
+		ResultCacheTTL:        conf.CompileCacheTTL,
+		MaxRetry:              conf.MaxRetry,
+		QueueWeights:          parseQueueKeyValues(conf.QueueWeights),
+		QueueConcurrency:      parseQueueKeyValues(conf.QueueConcurrency),
+		MemoryBudget:          *conf.MemoryBudget,
+		Executor:              executor,
+	})
+}
+
+// newCoordinator 在协调节点模式下创建协调节点
+func newCoordinator(conf *config.Config) *cluster.Coordinator {
+	if !conf.Coordinator {
+		return nil
+	}
+	if conf.NodeTimeout < minNodeTimeout {
+		logger.Sugar().Fatalf("-node-timeout should be at least %v", minNodeTimeout)
+	}
+	return cluster.NewCoordinator(cluster.CoordinatorConfig{
+		NodeTimeout: conf.NodeTimeout,
+		Logger:      logger,
 	})
 }
+
+// newTenants 在配置了租户时加载租户，未配置时不启用认证
+func newTenants(conf *config.Config, fs filestore.FileStore) *tenant.Registry {
+	if conf.TenantConf == "" {
+		return nil
+	}
+	r, err := tenant.ReadRegistry(conf.TenantConf, fs)
+	if err != nil {
+		logger.Sugar().Fatal("load tenants failed ", err)
+	}
+	logger.Sugar().Info("Load tenants:", conf.TenantConf)
+	return r
+}
+
+// newFederation 在配置了节点时创建转发器
+func newFederation(conf *config.Config, fs filestore.FileStore) *cluster.Federation {
+	if len(conf.Peers) == 0 {
+		return nil
+	}
+	if conf.Coordinator {
+		logger.Sugar().Fatal("-peers cannot be used together with -coordinator")
+	}
+	if conf.PeerCheckInterval < minPeerCheckInterval {
+		logger.Sugar().Fatalf("-peer-check-interval should be at least %v", minPeerCheckInterval)
+	}
+	return cluster.NewFederation(cluster.FederationConfig{
+		Peers:         conf.Peers,
+		APIKey:        conf.APIKey,
+		CheckInterval: conf.PeerCheckInterval,
+		FileStore:     fs,
+		Logger:        logger,
+	})
+}
+
+// newNode 在工作节点模式下向协调节点注册并开始获取请求
+func newNode(conf *config.Config, work worker.Worker, fs filestore.FileStore) *cluster.Node {
+	if conf.CoordinatorAddr == "" {
+		return nil
+	}
+	name := conf.NodeName
+	if name == "" {
+		name, _ = os.Hostname()
+	}
+	node := cluster.NewNode(cluster.NodeConfig{
+		Coordinator: conf.CoordinatorAddr,
+		APIKey:      conf.APIKey,
+		Name:        name,
+		Capacity:    conf.Parallelism,
+		Worker:      work,
+		FileStore:   fs,
+		SrcPrefix:   conf.SrcPrefix,
+		Logger:      logger,
+	})
+	node.Start()
+	logger.Sugar().Infof("Started node %s fetching requests from %s", name, conf.CoordinatorAddr)
+	return node
+}
+
+// parseQueueKeyValues 解析 key=value 形式的队列键配置
+func parseQueueKeyValues(s []string) map[string]int {
+	rt := make(map[string]int, len(s))
+	for _, kv := range s {
+		k, v, ok := strings.Cut(kv, "=")
+		n, err := strconv.Atoi(v)
+		if !ok || err != nil || n < 0 {
+			logger.Sugar().Fatal("invalid queue key config ", kv)
+		}
+		rt[k] = n
+	}
+	return rt
+}
@@ -1,17 +1,24 @@
 package main
 
 import (
+	"github.com/gin-gonic/gin"
+	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
 	"github.com/lxhcaicai/loj-judge/env/pool"
+	"github.com/lxhcaicai/loj-judge/envexec"
 	"github.com/lxhcaicai/loj-judge/filestore"
 	"github.com/lxhcaicai/loj-judge/worker"
 	"github.com/prometheus/client_golang/prometheus"
+	ginprometheus "github.com/zsais/go-gin-prometheus"
+	"os"
 	"sync"
+	"time"
 )
 
 const (
 	metricsNamespace   = "executorserver"
 	execSubsystem      = "exec"
 	filestoreSubsystem = "file"
+	envSubsystem       = "environment"
 )
 
 var (
@@ -22,6 +29,9 @@ var (
 		0.4, 0.6, 0.8, 1.0, 1.5, 2, 5, 10,
 	}
 
+	// 256k -> 4g
+	memoryBucket = prometheus.ExponentialBuckets(1<<18, 2, 15)
+
 	fileSizeBucket = prometheus.ExponentialBuckets(1<<8, 2, 20)
 
 	execErrorCount = prometheus.NewCounter(prometheus.CounterOpts{
@@ -40,14 +50,104 @@ var (
 	}, []string{"status"})
 
 	execMemHist = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: execSubsystem,
+		Name:      "memory_bytes",
+		Help:      "Histogram for the command execution max memory",
+		Buckets:   memoryBucket,
+	}, []string{"status"})
+
+	fsSizeHist = prometheus.NewHistogram(prometheus.HistogramOpts{
 		Namespace: metricsNamespace,
 		Subsystem: filestoreSubsystem,
 		Name:      "size_bytes",
-		Help:      "Histgram for the file size created in the file store",
+		Help:      "Histogram for the file size created in the file store",
 		Buckets:   fileSizeBucket,
-	}, []string{"status"})
+	})
+
+	fsCurrentCount = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: filestoreSubsystem,
+		Name:      "current_count",
+		Help:      "Number of files in the file store",
+	})
+
+	fsCurrentBytes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: filestoreSubsystem,
+		Name:      "current_bytes",
+		Help:      "Total size of files in the file store",
+	})
+
+	envBuildTimeHist = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: envSubsystem,
+		Name:      "build_time_seconds",
+		Help:      "Histogram for the time to build a new environment",
+		Buckets:   timeBuckets,
+	})
+
+	envBuildErrorCount = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: envSubsystem,
+		Name:      "build_error_count",
+		Help:      "Number of environment builds failed",
+	})
+
+	envCreatedCount = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: envSubsystem,
+		Name:      "count",
+		Help:      "Number of environment created",
+	})
+
+	envInUseCount = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: envSubsystem,
+		Name:      "in_use_count",
+		Help:      "Number of environment taken from the pool",
+	})
+
+	envGetTimeHist = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: envSubsystem,
+		Name:      "get_time_seconds",
+		Help:      "Histogram for the time to get an environment from the pool",
+		Buckets:   timeBuckets,
+	})
+
+	envGetErrorCount = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: envSubsystem,
+		Name:      "get_error_count",
+		Help:      "Number of environment get from the pool failed",
+	})
 )
 
+func init() {
+	prometheus.MustRegister(
+		execErrorCount, execTimeHist, execMemHist,
+		fsSizeHist, fsCurrentCount, fsCurrentBytes,
+		envBuildTimeHist, envBuildErrorCount, envCreatedCount,
+		envInUseCount, envGetTimeHist, envGetErrorCount,
+	)
+}
+
+// initGinMetrics 注册 gin 请求指标以及 /metrics 路由
+func initGinMetrics(r *gin.Engine) {
+	p := ginprometheus.NewPrometheus("gin")
+	// 使用路由路径作为标签，避免文件 id 等参数导致标签数量爆炸
+	p.ReqCntURLLabelMappingFn = func(c *gin.Context) string {
+		return c.FullPath()
+	}
+	p.Use(r)
+}
+
+// initGRPCMetrics 为 gRPC 服务开启处理时间统计
+func initGRPCMetrics() {
+	grpc_prometheus.EnableHandlingTimeHistogram(grpc_prometheus.WithHistogramBuckets(timeBuckets))
+}
+
 type metricsFileStore struct {
 	mu sync.Mutex
 	filestore.FileStore
@@ -61,14 +161,97 @@ func newMetricsFileStore(fs filestore.FileStore) filestore.FileStore {
 	}
 }
 
+func (m *metricsFileStore) Add(name, path string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id, err := m.FileStore.Add(name, path)
+	if err != nil {
+		return "", err
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return id, nil
+	}
+	s := fi.Size()
+	if old, ok := m.fileSize[id]; ok {
+		fsCurrentCount.Dec()
+		fsCurrentBytes.Sub(float64(old))
+	}
+	m.fileSize[id] = s
+
+	fsSizeHist.Observe(float64(s))
+	fsCurrentCount.Inc()
+	fsCurrentBytes.Add(float64(s))
+	return id, nil
+}
+
+func (m *metricsFileStore) Remove(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	success := m.FileStore.Remove(id)
+	s, ok := m.fileSize[id]
+	delete(m.fileSize, id)
+	if ok {
+		fsCurrentCount.Dec()
+		fsCurrentBytes.Sub(float64(s))
+	}
+	return success
+}
+
 type metriceEnvBuilder struct {
 	pool.EnvBuilder
 }
 
+func (b *metriceEnvBuilder) Build() (pool.Environment, error) {
+	start := time.Now()
+	e, err := b.EnvBuilder.Build()
+	if err != nil {
+		envBuildErrorCount.Inc()
+		return nil, err
+	}
+	envBuildTimeHist.Observe(time.Since(start).Seconds())
+	envCreatedCount.Inc()
+	return &metricsEnvironment{e}, nil
+}
+
+// metricsEnvironment 在环境被销毁时更新环境数量
+type metricsEnvironment struct {
+	pool.Environment
+}
+
+func (e *metricsEnvironment) Destroy() error {
+	envCreatedCount.Dec()
+	return e.Environment.Destroy()
+}
+
 type metricsEnvPool struct {
 	worker.EnvironmentPool
 }
 
+func (p *metricsEnvPool) Get() (envexec.Environment, error) {
+	start := time.Now()
+	e, err := p.EnvironmentPool.Get()
+	if err != nil {
+		envGetErrorCount.Inc()
+		return nil, err
+	}
+	envGetTimeHist.Observe(time.Since(start).Seconds())
+	envInUseCount.Inc()
+	return e, nil
+}
+
+func (p *metricsEnvPool) Put(e envexec.Environment) {
+	envInUseCount.Dec()
+	p.EnvironmentPool.Put(e)
+}
+
+func (p *metricsEnvPool) Destroy(e envexec.Environment) {
+	envInUseCount.Dec()
+	p.EnvironmentPool.Destroy(e)
+}
+
 func execObserve(res worker.Response) {
 	if res.Error != nil {
 		execErrorCount.Inc()
@@ -19,6 +19,10 @@ type CmdFile struct {
 	Max     *int64  `json:"max"`
 	Pipe    bool    `json:"pipe"`
 	Symlink *string `json:"symlink"`
+	Stage   *string `json:"stage,omitempty"` // 与 name 一起引用之前阶段的输出文件
+
+	StreamIn  bool `json:"streamIn,omitempty"`
+	StreamOut bool `json:"streamOut,omitempty"`
 }
 
 // CMD 定义在envexec中使用的启动程序的命令和限制
@@ -44,6 +48,43 @@ type Cmd struct {
 	CopyOutCached []string `json:"copyOutCached"`
 	CopyOutMax    uint64   `json:"copyOutMax"`
 	CopOutDir     string   `json:"copOutDir"`
+
+	Compare *Compare `json:"compare,omitempty"`
+	Checker *Checker `json:"checker,omitempty"`
+
+	// Language 引用服务端预设的语言模板，展开后与 args / env 等合并
+	Language *LanguageRef `json:"language,omitempty"`
+
+	// Cache 为真时按照展开后的命令与输入内容缓存成功的结果 (用于编译)，
+	// 每次命中都返回新的 fileIds，请求方可以在使用后删除
+	Cache bool `json:"cache,omitempty"`
+}
+
+// LanguageRef 定义对预设语言的引用，名称为空时使用预设的源文件与可执行文件名
+type LanguageRef struct {
+	Name           string `json:"name"`
+	Stage          string `json:"stage,omitempty"` // compile / run (默认)
+	SourceName     string `json:"sourceName,omitempty"`
+	ExecutableName string `json:"executableName,omitempty"`
+}
+
+// Compare 定义执行成功后输出文件与答案的比较
+type Compare struct {
+	Name       string   `json:"name"`
+	Answer     *CmdFile `json:"answer"`
+	Mode       string   `json:"mode,omitempty"` // exact / ignoreTrailingSpace (默认) / token / float
+	Epsilon    float64  `json:"epsilon,omitempty"`
+	KeepOutput bool     `json:"keepOutput,omitempty"`
+}
+
+// Checker 定义执行成功后运行的 testlib 评测程序，
+// 评测程序以 input output answer 作为参数，通过标准错误输出信息
+type Checker struct {
+	Cmd        Cmd      `json:"cmd"`
+	Name       string   `json:"name"`
+	Input      *CmdFile `json:"input,omitempty"`
+	Answer     *CmdFile `json:"answer"`
+	KeepOutput bool     `json:"keepOutput,omitempty"`
 }
 
 // PipeIndex 定义管道fd的索引
@@ -61,22 +102,99 @@ type PipeMap struct {
 	Proxy bool      `json:"proxy"`
 }
 
+// Callback 定义执行完成后接收结果的回调地址
+type Callback struct {
+	URL    string `json:"url"`
+	Secret string `json:"secret,omitempty"`
+}
+
 // Request 定义单个worker请求
 type Request struct {
 	RequestID   string    `json:"requestId"`
 	Cmd         []Cmd     `json:"cmd"`
 	PipeMapping []PipeMap `json:"pipeMapping"`
+	Callback    *Callback `json:"callback,omitempty"`
+
+	// Priority 为优先级类别 (high / normal (默认) / low)，QueueKey 为公平调度使用的队列键
+	Priority string `json:"priority,omitempty"`
+	QueueKey string `json:"queueKey,omitempty"`
+
+	// QueueTimeout 为在队列中等待的最长时间 (纳秒)，超时后返回 queue timeout 错误
+	QueueTimeout uint64 `json:"queueTimeout,omitempty"`
+
+	// Interactive 非空时 cmd[0] 为选手程序，cmd[1] 为交互器
+	Interactive *Interactive `json:"interactive,omitempty"`
+
+	// ShareEnv 为真时 cmd 在同一个环境中依次执行，之前命令写入的文件对之后的命令可见
+	ShareEnv bool `json:"shareEnv,omitempty"`
+
+	// Stages 非空时按顺序执行各个阶段，cmd 等被忽略
+	Stages []Stage `json:"stages,omitempty"`
+
+	// Subtasks 将阶段作为测试点分组计分，响应中给出 verdict
+	Subtasks []Subtask `json:"subtasks,omitempty"`
+}
+
+// Subtask 定义由若干测试点阶段组成的子任务
+type Subtask struct {
+	Name         string   `json:"name"`
+	Score        float64  `json:"score"`
+	Policy       string   `json:"policy,omitempty"` // sum (默认) / min / allOrNothing
+	Stages       []string `json:"stages"`
+	Dependencies []string `json:"dependencies,omitempty"`
+}
+
+// Stage 定义多阶段请求中的一个阶段
+type Stage struct {
+	Name         string       `json:"name"`
+	Cmd          []Cmd        `json:"cmd"`
+	PipeMapping  []PipeMap    `json:"pipeMapping,omitempty"`
+	Interactive  *Interactive `json:"interactive,omitempty"`
+	ShareEnv     bool         `json:"shareEnv,omitempty"`
+	Parallel     bool         `json:"parallel,omitempty"`
+	AllowFailure bool         `json:"allowFailure,omitempty"`
+}
+
+// Interactive 定义交互题的运行方式
+type Interactive struct {
+	Transcript      bool  `json:"transcript,omitempty"`
+	TranscriptLimit int64 `json:"transcriptLimit,omitempty"`
 }
 
 // 定义单个请求的worker响应
 type Response struct {
-	RequestID string   `json:"requestId"`
-	Results   []Result `json:"results"`
-	ErrorMsg  string   `json:"error,omitempty"`
+	RequestID string        `json:"requestId"`
+	Results   []Result      `json:"results"`
+	Stages    []StageResult `json:"stages,omitempty"`
+	Verdict   *Verdict      `json:"verdict,omitempty"`
+	ErrorMsg  string        `json:"error,omitempty"`
 
 	mmap bool
 }
 
+// Verdict 定义设置子任务时的最终结论
+type Verdict struct {
+	Status   Status          `json:"status"`
+	Score    float64         `json:"score"`
+	Subtasks []SubtaskResult `json:"subtasks"`
+}
+
+// SubtaskResult 定义单个子任务的得分
+type SubtaskResult struct {
+	Name    string  `json:"name"`
+	Status  Status  `json:"status"`
+	Score   float64 `json:"score"`
+	Skipped bool    `json:"skipped,omitempty"`
+}
+
+// StageResult 定义单个阶段的结果
+type StageResult struct {
+	Name     string   `json:"name"`
+	Results  []Result `json:"results"`
+	ErrorMsg string   `json:"error,omitempty"`
+	Skipped  bool     `json:"skipped,omitempty"`
+}
+
 // Status 为 envexec.Status 提供JSON封装
 type Status envexec.Status
 
@@ -100,6 +218,10 @@ type Result struct {
 	Status     Status              `json:"status"`
 	ExitStatus int                 `json:"exitStatus"`
 	Error      string              `json:"error,omitempty"`
+	Message    string              `json:"message,omitempty"`
+	Score      float64             `json:"score,omitempty"`
+	Cached     bool                `json:"cached,omitempty"`
+	Retry      int                 `json:"retry,omitempty"`
 	Time       uint64              `json:"time"`
 	Memory     uint64              `json:"memory"`
 	RunTime    uint64              `json:"runTime"`
@@ -126,31 +248,116 @@ func (r *Response) Close() {
 	if !r.mmap {
 		return
 	}
+	r.closeResults()
+}
+
+func (r *Response) closeResults() {
 	for _, res := range r.Results {
 		res.Close()
 	}
+	for _, s := range r.Stages {
+		for _, res := range s.Results {
+			res.Close()
+		}
+	}
 }
 
 // ConvertRequest 将json请求转换为worker请求
 func ConvertRequest(r *Request, srcPrefix []string) (*worker.Request, error) {
 	req := &worker.Request{
-		RequestID:   r.RequestID,
-		Cmd:         make([]worker.Cmd, 0, len(r.Cmd)),
-		PipeMapping: make([]worker.PipeMap, 0, len(r.PipeMapping)),
+		RequestID:    r.RequestID,
+		QueueKey:     r.QueueKey,
+		QueueTimeout: time.Duration(r.QueueTimeout),
+		ShareEnv:     r.ShareEnv,
 	}
-	for _, c := range r.Cmd {
-		wc, err := convertCmd(c, srcPrefix)
+	var err error
+	if req.Priority, err = convertPriority(r.Priority); err != nil {
+		return nil, err
+	}
+	req.Cmd, req.PipeMapping, req.Interactive, err = convertCmds(r.Cmd, r.PipeMapping, r.Interactive, srcPrefix)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range r.Stages {
+		ws := worker.Stage{
+			Name:         s.Name,
+			ShareEnv:     s.ShareEnv,
+			Parallel:     s.Parallel,
+			AllowFailure: s.AllowFailure,
+		}
+		ws.Cmd, ws.PipeMapping, ws.Interactive, err = convertCmds(s.Cmd, s.PipeMapping, s.Interactive, srcPrefix)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("stage %s: %w", s.Name, err)
 		}
-		req.Cmd = append(req.Cmd, wc)
+		req.Stages = append(req.Stages, ws)
 	}
-	for _, p := range r.PipeMapping {
-		req.PipeMapping = append(req.PipeMapping, convertPipe(p))
+	for _, s := range r.Subtasks {
+		st, err := convertSubtask(s)
+		if err != nil {
+			return nil, err
+		}
+		req.Subtasks = append(req.Subtasks, st)
 	}
 	return req, nil
 }
 
+func convertPriority(p string) (worker.Priority, error) {
+	switch p {
+	case "", "normal":
+		return worker.PriorityNormal, nil
+	case "high":
+		return worker.PriorityHigh, nil
+	case "low":
+		return worker.PriorityLow, nil
+	default:
+		return 0, fmt.Errorf("unknown priority %s", p)
+	}
+}
+
+func convertSubtask(s Subtask) (worker.Subtask, error) {
+	var policy worker.ScorePolicy
+	switch s.Policy {
+	case "", "sum":
+		policy = worker.ScoreSum
+	case "min":
+		policy = worker.ScoreMin
+	case "allOrNothing":
+		policy = worker.ScoreAllOrNothing
+	default:
+		return worker.Subtask{}, fmt.Errorf("subtask %s: unknown policy %s", s.Name, s.Policy)
+	}
+	return worker.Subtask{
+		Name:         s.Name,
+		Score:        s.Score,
+		Policy:       policy,
+		Stages:       s.Stages,
+		Dependencies: s.Dependencies,
+	}, nil
+}
+
+func convertCmds(cmds []Cmd, pipes []PipeMap, it *Interactive, srcPrefix []string) ([]worker.Cmd, []worker.PipeMap, *worker.Interactive, error) {
+	wc := make([]worker.Cmd, 0, len(cmds))
+	for _, c := range cmds {
+		cc, err := convertCmd(c, srcPrefix)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		wc = append(wc, cc)
+	}
+	pm := make([]worker.PipeMap, 0, len(pipes))
+	for _, p := range pipes {
+		pm = append(pm, convertPipe(p))
+	}
+	var wi *worker.Interactive
+	if it != nil {
+		wi = &worker.Interactive{
+			Transcript:      it.Transcript,
+			TranscriptLimit: envexec.Size(it.TranscriptLimit),
+		}
+	}
+	return wc, pm, wi, nil
+}
+
 func convertPipe(p PipeMap) worker.PipeMap {
 	return worker.PipeMap{
 		In: worker.PipeIndex{
@@ -168,6 +375,9 @@ func convertPipe(p PipeMap) worker.PipeMap {
 }
 
 func convertCmd(c Cmd, srcPrefix []string) (worker.Cmd, error) {
+	if c.Language != nil {
+		return worker.Cmd{}, fmt.Errorf("language %s: presets are not expanded", c.Language.Name)
+	}
 	clockLimit := c.ClockLimit
 	if c.RealCPULimit > 0 {
 		clockLimit = c.ClockLimit
@@ -189,6 +399,7 @@ func convertCmd(c Cmd, srcPrefix []string) (worker.Cmd, error) {
 		CopyOutCached:     convertCopyOut(c.CopyOutCached),
 		CopyOutMax:        c.CopyOutMax,
 		CopyOutDir:        c.CopOutDir,
+		Cache:             c.Cache,
 	}
 	for _, f := range c.Files {
 		cf, err := convertCmdFile(f, srcPrefix)
@@ -197,6 +408,20 @@ func convertCmd(c Cmd, srcPrefix []string) (worker.Cmd, error) {
 		}
 		w.Files = append(w.Files, cf)
 	}
+	if c.Compare != nil {
+		cmp, err := convertCompare(c.Compare, srcPrefix)
+		if err != nil {
+			return w, err
+		}
+		w.Compare = cmp
+	}
+	if c.Checker != nil {
+		chk, err := convertChecker(c.Checker, srcPrefix)
+		if err != nil {
+			return w, err
+		}
+		w.Checker = chk
+	}
 	if c.CopyIn != nil {
 		w.CopyIn = make(map[string]worker.CmdFile)
 		w.Symlinks = make(map[string]string)
@@ -215,25 +440,86 @@ func convertCmd(c Cmd, srcPrefix []string) (worker.Cmd, error) {
 	return w, nil
 }
 
+func convertCompare(c *Compare, srcPrefix []string) (*worker.Compare, error) {
+	if c.Answer == nil {
+		return nil, fmt.Errorf("compare: answer file not provided")
+	}
+	if c.Answer.Max != nil || c.Answer.StreamIn || c.Answer.StreamOut {
+		return nil, fmt.Errorf("compare: answer should be a local, memory or cached file")
+	}
+	answer, err := convertCmdFile(c.Answer, srcPrefix)
+	if err != nil {
+		return nil, err
+	}
+	var mode worker.CompareMode
+	switch c.Mode {
+	case "", "ignoreTrailingSpace":
+		mode = worker.CompareIgnoreTrailingSpace
+	case "exact":
+		mode = worker.CompareExact
+	case "token":
+		mode = worker.CompareToken
+	case "float":
+		mode = worker.CompareFloat
+	default:
+		return nil, fmt.Errorf("compare: unknown mode %s", c.Mode)
+	}
+	return &worker.Compare{
+		Name:       c.Name,
+		Answer:     answer,
+		Mode:       mode,
+		Epsilon:    c.Epsilon,
+		KeepOutput: c.KeepOutput,
+	}, nil
+}
+
+func convertChecker(c *Checker, srcPrefix []string) (*worker.Checker, error) {
+	if c.Answer == nil {
+		return nil, fmt.Errorf("checker: answer file not provided")
+	}
+	if c.Cmd.Checker != nil || c.Cmd.Compare != nil {
+		return nil, fmt.Errorf("checker: nested compare or checker is not allowed")
+	}
+	isInput := func(f *CmdFile) bool {
+		return f == nil || (f.Max == nil && !f.StreamIn && !f.StreamOut)
+	}
+	if !isInput(c.Input) || !isInput(c.Answer) {
+		return nil, fmt.Errorf("checker: input and answer should be a local, memory or cached file")
+	}
+	cmd, err := convertCmd(c.Cmd, srcPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("checker: %w", err)
+	}
+	input, err := convertCmdFile(c.Input, srcPrefix)
+	if err != nil {
+		return nil, err
+	}
+	answer, err := convertCmdFile(c.Answer, srcPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return &worker.Checker{
+		Cmd:        cmd,
+		Name:       c.Name,
+		Input:      input,
+		Answer:     answer,
+		KeepOutput: c.KeepOutput,
+	}, nil
+}
+
 func ConvertResponse(r worker.Response, mmap bool) (ret Response, err error) {
 	// 在错误情况下，释放所有资源
 	defer func() {
 		if err != nil {
-			for _, r := range ret.Results {
-				r.Close()
-			}
-			for _, r := range r.Results {
-				for _, f := range r.Files {
-					f.Close()
-					os.Remove(f.Name())
-				}
+			ret.closeResults()
+			removeFiles(r.Results)
+			for _, s := range r.Stages {
+				removeFiles(s.Results)
 			}
 		}
 		// 如果不需要mmap，关闭所有文件
 		if !mmap {
-			for _, r := range ret.Results {
-				r.Close()
-			}
+			ret.closeResults()
 		}
 	}()
 
@@ -249,17 +535,64 @@ func ConvertResponse(r worker.Response, mmap bool) (ret Response, err error) {
 		}
 		ret.Results = append(ret.Results, res)
 	}
+	for _, s := range r.Stages {
+		sr := StageResult{
+			Name:    s.Name,
+			Results: make([]Result, 0, len(s.Results)),
+			Skipped: s.Skipped,
+		}
+		if s.Error != nil {
+			sr.ErrorMsg = s.Error.Error()
+		}
+		for _, r := range s.Results {
+			res, err := convertResult(r, mmap)
+			if err != nil {
+				return ret, err
+			}
+			sr.Results = append(sr.Results, res)
+		}
+		ret.Stages = append(ret.Stages, sr)
+	}
+	if v := r.Verdict; v != nil {
+		ret.Verdict = &Verdict{
+			Status:   Status(v.Status),
+			Score:    v.Score,
+			Subtasks: make([]SubtaskResult, 0, len(v.Subtasks)),
+		}
+		for _, s := range v.Subtasks {
+			ret.Verdict.Subtasks = append(ret.Verdict.Subtasks, SubtaskResult{
+				Name:    s.Name,
+				Status:  Status(s.Status),
+				Score:   s.Score,
+				Skipped: s.Skipped,
+			})
+		}
+	}
 	if r.Error != nil {
 		ret.ErrorMsg = r.Error.Error()
 	}
 	return ret, nil
 }
 
+// removeFiles 关闭并删除 worker 结果中的临时文件
+func removeFiles(results []worker.Result) {
+	for _, r := range results {
+		for _, f := range r.Files {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}
+}
+
 func convertResult(r worker.Result, mmap bool) (Result, error) {
 	res := Result{
 		Status:     Status(r.Status),
 		ExitStatus: r.ExitStatus,
 		Error:      r.Error,
+		Message:    r.Message,
+		Score:      r.Score,
+		Cached:     r.Cached,
+		Retry:      r.Retry,
 		Time:       uint64(r.Time),
 		RunTime:    uint64(r.RunTime),
 		Memory:     uint64(r.Memory),
@@ -287,6 +620,8 @@ func convertCmdFile(f *CmdFile, srcPrefix []string) (worker.CmdFile, error) {
 	switch {
 	case f == nil:
 		return nil, nil
+	case f.StreamIn || f.StreamOut:
+		return nil, fmt.Errorf("stream file is only supported by the stream endpoint")
 	case f.Src != nil:
 		if len(srcPrefix) != 0 {
 			ok, err := CheckPathPrefixes(*f.Src, srcPrefix)
@@ -302,6 +637,8 @@ func convertCmdFile(f *CmdFile, srcPrefix []string) (worker.CmdFile, error) {
 		return &worker.MemoryFile{Content: []byte(*f.Content)}, nil
 	case f.FileID != nil:
 		return &worker.CachedFile{FileID: *f.FileID}, nil
+	case f.Stage != nil && f.Name != nil:
+		return &worker.StageFile{Stage: *f.Stage, Name: *f.Name}, nil
 	case f.Max != nil && f.Name != nil:
 		return &worker.Collector{Name: *f.Name, Max: envexec.Size(*f.Max), Pipe: f.Pipe}, nil
 	default:
@@ -351,3 +688,59 @@ func convertCopyOut(copyOut []string) []worker.CmdCopyOutFile {
 	}
 	return rt
 }
+
+// QueueStats 定义等待队列的状态
+type QueueStats struct {
+	Waiting map[string]int          `json:"waiting"` // 优先级类别 -> 等待的请求数量
+	Keys    map[string]QueueKeyStat `json:"keys"`
+	Jobs    int                     `json:"jobs"`   // 排队中的异步任务数量
+	Memory  uint64                  `json:"memory"` // 执行中的请求占用的内存预算
+}
+
+// WorkerState 定义 worker 的运行状态
+type WorkerState struct {
+	Parallelism int  `json:"parallelism"`
+	Running     int  `json:"running"`
+	Waiting     int  `json:"waiting"`
+	Paused      bool `json:"paused"`
+	Draining    bool `json:"draining"`
+	Drained     bool `json:"drained"` // 排空完成，可以停止
+}
+
+// ConvertWorkerState 将 worker 队列状态转换为 worker 的运行状态
+func ConvertWorkerState(s worker.QueueStats) WorkerState {
+	ret := WorkerState{
+		Parallelism: s.Parallelism,
+		Running:     s.Running,
+		Paused:      s.Paused,
+		Draining:    s.Draining,
+		Drained:     s.Drained,
+	}
+	for _, n := range s.Waiting {
+		ret.Waiting += n
+	}
+	return ret
+}
+
+// QueueKeyStat 定义单个队列键的状态
+type QueueKeyStat struct {
+	Waiting int `json:"waiting"`
+	Running int `json:"running"`
+}
+
+// ConvertQueueStats 将 worker 队列状态转换为 json 形式
+func ConvertQueueStats(s worker.QueueStats, jobs int) QueueStats {
+	ret := QueueStats{
+		Waiting: make(map[string]int, len(s.Waiting)),
+		Keys:    make(map[string]QueueKeyStat, len(s.Keys)),
+		Jobs:    jobs,
+		Memory:  uint64(s.Memory),
+	}
+	for p, n := range s.Waiting {
+		ret.Waiting[p.String()] = n
+	}
+	for k, v := range s.Keys {
+		ret.Keys[k] = QueueKeyStat{Waiting: v.Waiting, Running: v.Running}
+	}
+	return ret
+}
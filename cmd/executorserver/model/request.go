@@ -0,0 +1,301 @@
+package model
+
+import (
+	"fmt"
+	"github.com/lxhcaicai/loj-judge/envexec"
+	"github.com/lxhcaicai/loj-judge/worker"
+	"os"
+	"time"
+)
+
+// ConvertWorkerRequest 将 worker 请求转换回 json 请求，用于转发到集群中的节点。
+// 本地文件的内容被读出，流式的输入输出不能转发
+func ConvertWorkerRequest(r *worker.Request) (*Request, error) {
+	req := &Request{
+		RequestID:    r.RequestID,
+		Priority:     r.Priority.String(),
+		QueueKey:     r.QueueKey,
+		QueueTimeout: uint64(r.QueueTimeout),
+		ShareEnv:     r.ShareEnv,
+	}
+	var err error
+	req.Cmd, req.PipeMapping, req.Interactive, err = convertWorkerCmds(r.Cmd, r.PipeMapping, r.Interactive)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range r.Stages {
+		ms := Stage{
+			Name:         s.Name,
+			ShareEnv:     s.ShareEnv,
+			Parallel:     s.Parallel,
+			AllowFailure: s.AllowFailure,
+		}
+		ms.Cmd, ms.PipeMapping, ms.Interactive, err = convertWorkerCmds(s.Cmd, s.PipeMapping, s.Interactive)
+		if err != nil {
+			return nil, fmt.Errorf("stage %s: %w", s.Name, err)
+		}
+		req.Stages = append(req.Stages, ms)
+	}
+	for _, s := range r.Subtasks {
+		policy := "sum"
+		switch s.Policy {
+		case worker.ScoreMin:
+			policy = "min"
+		case worker.ScoreAllOrNothing:
+			policy = "allOrNothing"
+		}
+		req.Subtasks = append(req.Subtasks, Subtask{
+			Name:         s.Name,
+			Score:        s.Score,
+			Policy:       policy,
+			Stages:       s.Stages,
+			Dependencies: s.Dependencies,
+		})
+	}
+	return req, nil
+}
+
+func convertWorkerCmds(cmds []worker.Cmd, pipes []worker.PipeMap, it *worker.Interactive) ([]Cmd, []PipeMap, *Interactive, error) {
+	mc := make([]Cmd, 0, len(cmds))
+	for _, c := range cmds {
+		cc, err := convertWorkerCmd(c)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		mc = append(mc, cc)
+	}
+	var pm []PipeMap
+	for _, p := range pipes {
+		pm = append(pm, PipeMap{
+			In:    PipeIndex{Index: p.In.Index, Fd: p.In.Fd},
+			Out:   PipeIndex{Index: p.Out.Index, Fd: p.Out.Fd},
+			Name:  p.Name,
+			Max:   int64(p.Limit),
+			Proxy: p.Proxy,
+		})
+	}
+	var mi *Interactive
+	if it != nil {
+		mi = &Interactive{
+			Transcript:      it.Transcript,
+			TranscriptLimit: int64(it.TranscriptLimit),
+		}
+	}
+	return mc, pm, mi, nil
+}
+
+func convertWorkerCmd(c worker.Cmd) (Cmd, error) {
+	m := Cmd{
+		Args:              c.Args,
+		Env:               c.Env,
+		Files:             make([]*CmdFile, 0, len(c.Files)),
+		TTY:               c.TTY,
+		CPULimit:          uint64(c.CPULimit),
+		ClockLimit:        uint64(c.ClockLimit),
+		MemoryLimit:       uint64(c.MemoryLimit),
+		StackLimit:        uint64(c.StackLimit),
+		ProcLimit:         c.ProcLimit,
+		CPURateLimit:      c.CPURateLimit,
+		CPUSetLimit:       c.CPUSetLimit,
+		StrictMemoryLimit: c.StrictMemoryLimit,
+		CopyOut:           convertWorkerCopyOut(c.CopyOut),
+		CopyOutCached:     convertWorkerCopyOut(c.CopyOutCached),
+		CopyOutMax:        c.CopyOutMax,
+		CopOutDir:         c.CopyOutDir,
+		Cache:             c.Cache,
+	}
+	for _, f := range c.Files {
+		mf, err := convertWorkerCmdFile(f)
+		if err != nil {
+			return m, err
+		}
+		m.Files = append(m.Files, mf)
+	}
+	if c.CopyIn != nil || c.Symlinks != nil {
+		m.CopyIn = make(map[string]CmdFile, len(c.CopyIn)+len(c.Symlinks))
+		for k, f := range c.CopyIn {
+			mf, err := convertWorkerCmdFile(f)
+			if err != nil {
+				return m, err
+			}
+			if mf != nil {
+				m.CopyIn[k] = *mf
+			}
+		}
+		for k, s := range c.Symlinks {
+			s := s
+			m.CopyIn[k] = CmdFile{Symlink: &s}
+		}
+	}
+	if c.Compare != nil {
+		answer, err := convertWorkerCmdFile(c.Compare.Answer)
+		if err != nil {
+			return m, err
+		}
+		mode := "ignoreTrailingSpace"
+		switch c.Compare.Mode {
+		case worker.CompareExact:
+			mode = "exact"
+		case worker.CompareToken:
+			mode = "token"
+		case worker.CompareFloat:
+			mode = "float"
+		}
+		m.Compare = &Compare{
+			Name:       c.Compare.Name,
+			Answer:     answer,
+			Mode:       mode,
+			Epsilon:    c.Compare.Epsilon,
+			KeepOutput: c.Compare.KeepOutput,
+		}
+	}
+	if c.Checker != nil {
+		cmd, err := convertWorkerCmd(c.Checker.Cmd)
+		if err != nil {
+			return m, fmt.Errorf("checker: %w", err)
+		}
+		input, err := convertWorkerCmdFile(c.Checker.Input)
+		if err != nil {
+			return m, err
+		}
+		answer, err := convertWorkerCmdFile(c.Checker.Answer)
+		if err != nil {
+			return m, err
+		}
+		m.Checker = &Checker{
+			Cmd:        cmd,
+			Name:       c.Checker.Name,
+			Input:      input,
+			Answer:     answer,
+			KeepOutput: c.Checker.KeepOutput,
+		}
+	}
+	return m, nil
+}
+
+func convertWorkerCmdFile(f worker.CmdFile) (*CmdFile, error) {
+	switch f := f.(type) {
+	case nil:
+		return nil, nil
+	case *worker.LocalFile:
+		b, err := os.ReadFile(f.Src)
+		if err != nil {
+			return nil, err
+		}
+		s := string(b)
+		return &CmdFile{Content: &s}, nil
+	case *worker.MemoryFile:
+		s := string(f.Content)
+		return &CmdFile{Content: &s}, nil
+	case *worker.CachedFile:
+		return &CmdFile{FileID: &f.FileID}, nil
+	case *worker.StageFile:
+		return &CmdFile{Stage: &f.Stage, Name: &f.Name}, nil
+	case *worker.Collector:
+		max := int64(f.Max)
+		return &CmdFile{Name: &f.Name, Max: &max, Pipe: f.Pipe}, nil
+	default:
+		return nil, fmt.Errorf("file %s can not be forwarded", f)
+	}
+}
+
+func convertWorkerCopyOut(copyOut []worker.CmdCopyOutFile) []string {
+	rt := make([]string, 0, len(copyOut))
+	for _, f := range copyOut {
+		if f.Optional {
+			rt = append(rt, f.Name+optionalSuffix)
+			continue
+		}
+		rt = append(rt, f.Name)
+	}
+	return rt
+}
+
+// ConvertModelResponse 将 json 响应转换回 worker 响应，
+// 输出文件的内容写入 dir 下的临时文件，与本地执行的结果相同
+func ConvertModelResponse(r Response, dir string) (ret worker.Response, err error) {
+	defer func() {
+		if err != nil {
+			removeFiles(ret.Results)
+			for _, s := range ret.Stages {
+				removeFiles(s.Results)
+			}
+		}
+	}()
+
+	ret = worker.Response{RequestID: r.RequestID}
+	if r.ErrorMsg != "" {
+		ret.Error = fmt.Errorf("%s", r.ErrorMsg)
+	}
+	for _, res := range r.Results {
+		wr, err := convertModelResult(res, dir)
+		ret.Results = append(ret.Results, wr)
+		if err != nil {
+			return ret, err
+		}
+	}
+	for _, s := range r.Stages {
+		sr := worker.StageResult{Name: s.Name, Skipped: s.Skipped}
+		if s.ErrorMsg != "" {
+			sr.Error = fmt.Errorf("%s", s.ErrorMsg)
+		}
+		for _, res := range s.Results {
+			wr, err := convertModelResult(res, dir)
+			sr.Results = append(sr.Results, wr)
+			if err != nil {
+				ret.Stages = append(ret.Stages, sr)
+				return ret, err
+			}
+		}
+		ret.Stages = append(ret.Stages, sr)
+	}
+	if v := r.Verdict; v != nil {
+		ret.Verdict = &worker.Verdict{
+			Status: envexec.Status(v.Status),
+			Score:  v.Score,
+		}
+		for _, s := range v.Subtasks {
+			ret.Verdict.Subtasks = append(ret.Verdict.Subtasks, worker.SubtaskResult{
+				Name:    s.Name,
+				Status:  envexec.Status(s.Status),
+				Score:   s.Score,
+				Skipped: s.Skipped,
+			})
+		}
+	}
+	return ret, nil
+}
+
+func convertModelResult(r Result, dir string) (worker.Result, error) {
+	res := worker.Result{
+		Status:     envexec.Status(r.Status),
+		ExitStatus: r.ExitStatus,
+		Error:      r.Error,
+		Message:    r.Message,
+		Score:      r.Score,
+		Cached:     r.Cached,
+		Retry:      r.Retry,
+		Time:       time.Duration(r.Time),
+		RunTime:    time.Duration(r.RunTime),
+		Memory:     worker.Size(r.Memory),
+		FileIDs:    r.FileIDs,
+		FileError:  r.FileError,
+	}
+	if r.Files != nil {
+		res.Files = make(map[string]*os.File, len(r.Files))
+		for k, content := range r.Files {
+			f, err := os.CreateTemp(dir, "result")
+			if err != nil {
+				return res, err
+			}
+			res.Files[k] = f
+			if _, err := f.WriteString(content); err != nil {
+				return res, err
+			}
+			if _, err := f.Seek(0, 0); err != nil {
+				return res, err
+			}
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,83 @@
+package restexecutor
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/model"
+	"github.com/lxhcaicai/loj-judge/worker"
+	"net/http"
+)
+
+// NewAdmin 创建用于在运行时控制 worker 的接口，例如滚动发布前排空
+func NewAdmin(worker worker.Worker) Register {
+	return &adminHandle{worker: worker}
+}
+
+type adminHandle struct {
+	worker worker.Worker
+}
+
+type parallelismRequest struct {
+	Parallelism int `json:"parallelism"`
+}
+
+type drainQuery struct {
+	Wait bool `form:"wait"` // 等待排空完成后返回
+}
+
+func (h *adminHandle) Register(r *gin.Engine) {
+	r.GET("/admin/worker", h.stateGet)
+	r.PUT("/admin/worker/parallelism", h.parallelismPut)
+	r.POST("/admin/worker/pause", h.pausePost)
+	r.POST("/admin/worker/resume", h.resumePost)
+	r.POST("/admin/worker/drain", h.drainPost)
+}
+
+func (h *adminHandle) stateGet(c *gin.Context) {
+	c.JSON(http.StatusOK, h.state())
+}
+
+func (h *adminHandle) parallelismPut(c *gin.Context) {
+	var req parallelismRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Parallelism <= 0 {
+		c.AbortWithStatusJSON(http.StatusBadRequest, "parallelism must be positive")
+		return
+	}
+	h.worker.SetParallelism(req.Parallelism)
+	c.JSON(http.StatusOK, h.state())
+}
+
+func (h *adminHandle) pausePost(c *gin.Context) {
+	h.worker.Pause()
+	c.JSON(http.StatusOK, h.state())
+}
+
+func (h *adminHandle) resumePost(c *gin.Context) {
+	h.worker.Resume()
+	c.JSON(http.StatusOK, h.state())
+}
+
+// drainPost 开始排空，之后的请求返回 503。排空完成后状态中 drained 为真
+func (h *adminHandle) drainPost(c *gin.Context) {
+	var q drainQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	}
+	drained := h.worker.Drain()
+	if q.Wait {
+		select {
+		case <-drained:
+		case <-c.Request.Context().Done():
+			return
+		}
+	}
+	c.JSON(http.StatusOK, h.state())
+}
+
+func (h *adminHandle) state() model.WorkerState {
+	return model.ConvertWorkerState(h.worker.QueueStats())
+}
@@ -0,0 +1,149 @@
+package restexecutor
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/cluster"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/model"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultPollWait = 30 * time.Second
+	maxPollWait     = time.Minute
+)
+
+// NewCluster 创建协调节点供工作节点注册以及获取请求的接口
+func NewCluster(coord *cluster.Coordinator) Register {
+	return &clusterHandle{coord: coord}
+}
+
+type clusterHandle struct {
+	coord *cluster.Coordinator
+}
+
+type nodeURI struct {
+	NodeID string `uri:"id"`
+	TaskID string `uri:"tid"`
+}
+
+type pollQuery struct {
+	Wait time.Duration `form:"wait"`
+}
+
+func (h *clusterHandle) Register(r *gin.Engine) {
+	r.GET("/cluster/nodes", h.nodesGet)
+	r.POST("/cluster/nodes", h.nodePost)
+	r.DELETE("/cluster/nodes/:id", h.nodeDelete)
+	r.POST("/cluster/nodes/:id/heartbeat", h.heartbeatPost)
+	r.GET("/cluster/nodes/:id/task", h.taskGet)
+	r.POST("/cluster/nodes/:id/tasks/:tid", h.taskPost)
+}
+
+func (h *clusterHandle) nodesGet(c *gin.Context) {
+	c.JSON(http.StatusOK, h.coord.Nodes())
+}
+
+func (h *clusterHandle) nodePost(c *gin.Context) {
+	var reg cluster.Registration
+	if err := c.ShouldBindJSON(&reg); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	}
+	rt, err := h.coord.Register(reg)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, rt)
+}
+
+func (h *clusterHandle) nodeDelete(c *gin.Context) {
+	var uri nodeURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.coord.Deregister(uri.NodeID); err != nil {
+		clusterError(c, err)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+func (h *clusterHandle) heartbeatPost(c *gin.Context) {
+	var uri nodeURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	}
+	// 没有请求体时不检查节点执行中的请求
+	var req cluster.HeartbeatRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	}
+	rt, err := h.coord.Heartbeat(uri.NodeID, req)
+	if err != nil {
+		clusterError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, rt)
+}
+
+// taskGet 长轮询下一个请求，在等待时间内没有请求时返回 204
+func (h *clusterHandle) taskGet(c *gin.Context) {
+	var uri nodeURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	}
+	var q pollQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	}
+	if q.Wait <= 0 {
+		q.Wait = defaultPollWait
+	}
+	t, err := h.coord.Poll(c.Request.Context(), uri.NodeID, min(q.Wait, maxPollWait))
+	if err != nil {
+		clusterError(c, err)
+		return
+	}
+	if t == nil {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	c.JSON(http.StatusOK, t)
+}
+
+func (h *clusterHandle) taskPost(c *gin.Context) {
+	var uri nodeURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	}
+	var res model.Response
+	if err := c.ShouldBindJSON(&res); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.coord.Report(uri.NodeID, uri.TaskID, res); err != nil {
+		clusterError(c, err)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// clusterError 未知的节点或者请求返回 404，节点据此重新注册或者丢弃结果
+func clusterError(c *gin.Context, err error) {
+	if errors.Is(err, cluster.ErrUnknownNode) || errors.Is(err, cluster.ErrUnknownTask) {
+		c.AbortWithStatusJSON(http.StatusNotFound, err.Error())
+		return
+	}
+	c.Error(err)
+	c.AbortWithStatusJSON(http.StatusInternalServerError, err.Error())
+}
@@ -1,9 +1,16 @@
 package restexecutor
 
 import (
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/goccy/go-json"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/cluster"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/job"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/language"
 	"github.com/lxhcaicai/loj-judge/cmd/executorserver/model"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/tenant"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/webhook"
 	"github.com/lxhcaicai/loj-judge/filestore"
 	"github.com/lxhcaicai/loj-judge/worker"
 	"go.uber.org/zap"
@@ -14,10 +21,13 @@ type Register interface {
 	Register(engine *gin.Engine)
 }
 
-func New(worker worker.Worker, fs filestore.FileStore, srcPrefix []string, logger *zap.Logger) Register {
+func New(worker worker.Worker, fs filestore.FileStore, jobs *job.Store, notifier *webhook.Notifier, languages *language.Catalog, srcPrefix []string, logger *zap.Logger) Register {
 	return &handle{
 		worker:     worker,
 		fileHandle: fileHandle{fs: fs},
+		jobs:       jobs,
+		notifier:   notifier,
+		languages:  languages,
 		srcPrefix:  srcPrefix,
 		logger:     logger,
 	}
@@ -26,6 +36,9 @@ func New(worker worker.Worker, fs filestore.FileStore, srcPrefix []string, logge
 type handle struct {
 	worker worker.Worker
 	fileHandle
+	jobs      *job.Store
+	notifier  *webhook.Notifier
+	languages *language.Catalog
 	srcPrefix []string
 	logger    *zap.Logger
 }
@@ -33,6 +46,25 @@ type handle struct {
 func (h *handle) Register(r *gin.Engine) {
 
 	r.POST("/run", h.handleRun)
+	r.GET("/ws", h.handleWS)
+	r.GET("/stream", h.handleStream)
+
+	// Async job handle
+	r.POST("/jobs", h.jobPost)
+	r.GET("/jobs/:id", h.jobGet)
+	r.DELETE("/jobs/:id", h.jobDelete)
+
+	// Language presets
+	r.GET("/languages", h.languageGet)
+
+	// Queue depth
+	r.GET("/queue", h.queueGet)
+
+	// Tenant quota and usage
+	r.GET("/tenant", h.tenantGet)
+
+	// Webhook delivery log
+	r.GET("/webhooks", h.webhookGet)
 
 	// File handle
 	r.GET("/file", h.fileGet)
@@ -49,23 +81,33 @@ func (h *handle) handleRun(c *gin.Context) {
 		return
 	}
 
-	if len(req.Cmd) == 0 {
+	if len(req.Cmd) == 0 && len(req.Stages) == 0 {
 		c.AbortWithStatusJSON(http.StatusBadRequest, "no cmd provided")
 		return
 	}
-	r, err := model.ConvertRequest(&req, h.srcPrefix)
+	t := tenantOf(c)
+	r, err := h.convertRequest(t, &req)
 	if err != nil {
 		c.Error(err)
-		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		c.AbortWithStatusJSON(tenantStatus(err, http.StatusBadRequest), err.Error())
+		return
+	}
+	if err := t.Acquire(); err != nil {
+		c.AbortWithStatusJSON(tenantStatus(err, http.StatusInternalServerError), err.Error())
 		return
 	}
+	var res model.Response
+	defer func() {
+		t.Release(res)
+	}()
 	h.logger.Sugar().Debugf("request: %+v", r)
 	rtCh, _ := h.worker.Submit(c.Request.Context(), r)
 	rt := <-rtCh
 	h.logger.Sugar().Debugf("response: %+v", rt)
 	if rt.Error != nil {
+		h.notifier.Notify(req.Callback, model.Response{RequestID: rt.RequestID, ErrorMsg: rt.Error.Error()})
 		c.Error(rt.Error)
-		c.AbortWithStatusJSON(http.StatusInternalServerError, rt.Error.Error())
+		c.AbortWithStatusJSON(errorStatus(rt.Error), rt.Error.Error())
 		return
 	}
 
@@ -73,7 +115,7 @@ func (h *handle) handleRun(c *gin.Context) {
 	c.Status(http.StatusOK)
 	c.Header("Content-Type", "application/json; charset=utf-8")
 
-	res, err := model.ConvertResponse(rt, true)
+	res, err = model.ConvertResponse(rt, true)
 	if err != nil {
 		c.Error(err)
 		c.AbortWithStatusJSON(http.StatusInternalServerError, err.Error())
@@ -81,7 +123,90 @@ func (h *handle) handleRun(c *gin.Context) {
 	}
 	defer res.Close()
 
-	if err := json.NewEncoder(c.Writer).Encode(res.Results); err != nil {
+	// 多阶段请求按阶段返回结果，设置子任务时同时返回最终结论
+	var body any = res.Results
+	switch {
+	case len(req.Subtasks) > 0:
+		body = struct {
+			Stages  []model.StageResult `json:"stages"`
+			Verdict *model.Verdict      `json:"verdict"`
+		}{res.Stages, res.Verdict}
+	case len(req.Stages) > 0:
+		body = res.Stages
+	}
+	if err := json.NewEncoder(c.Writer).Encode(body); err != nil {
 		c.Error(err)
 	}
+	h.notifier.Notify(req.Callback, res)
+}
+
+// errorStatus 请求因为队列已满、等待超时、worker 排空或者没有可用节点没有执行时返回 503，以便客户端稍后重试
+func errorStatus(err error) int {
+	if errors.Is(err, worker.ErrQueueFull) || errors.Is(err, worker.ErrQueueTimeout) ||
+		errors.Is(err, worker.ErrDraining) || errors.Is(err, worker.ErrShutdown) ||
+		errors.Is(err, cluster.ErrNoPeer) {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+func (h *handle) convertRequest(t *tenant.Tenant, req *model.Request) (*worker.Request, error) {
+	return convertRequest(h.languages, h.srcPrefix, t, req)
+}
+
+// NewJobConvert 返回恢复异步任务时使用的转换函数，与提交时相同地展开语言预设、
+// 检查租户的限制并占用租户的名额，返回的函数在任务完成后释放名额
+func NewJobConvert(languages *language.Catalog, srcPrefix []string, tenants *tenant.Registry) func(*model.Request, string) (*worker.Request, func(model.Response), error) {
+	return func(req *model.Request, owner string) (*worker.Request, func(model.Response), error) {
+		var t *tenant.Tenant
+		if tenants != nil && owner != "" {
+			if t = tenants.Tenant(owner); t == nil {
+				return nil, nil, fmt.Errorf("tenant %s not found", owner)
+			}
+		}
+		r, err := convertRequest(languages, srcPrefix, t, req)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := t.Acquire(); err != nil {
+			return nil, nil, err
+		}
+		return r, t.Release, nil
+	}
+}
+
+// convertRequest 展开语言预设并转换为 worker 请求，
+// 启用认证时检查租户的限制，并以租户名称作为公平调度的队列键。
+// 语言预设的额外内存以及时间倍数在检查之后应用，不计入租户的限制
+func convertRequest(catalog *language.Catalog, srcPrefix []string, t *tenant.Tenant, req *model.Request) (*worker.Request, error) {
+	languages := language.Used(req)
+	padding, err := catalog.Apply(req)
+	if err != nil {
+		return nil, err
+	}
+	r, err := model.ConvertRequest(req, srcPrefix)
+	if err != nil {
+		return nil, err
+	}
+	r.Languages = languages
+	if err := t.Check(r); err != nil {
+		return nil, err
+	}
+	padding.Pad(r)
+	if t != nil {
+		r.QueueKey = t.Name
+	}
+	return r, nil
+}
+
+func (h *handle) languageGet(c *gin.Context) {
+	c.JSON(http.StatusOK, h.languages.List())
+}
+
+func (h *handle) queueGet(c *gin.Context) {
+	c.JSON(http.StatusOK, model.ConvertQueueStats(h.worker.QueueStats(), h.jobs.QueueLen()))
+}
+
+func (h *handle) webhookGet(c *gin.Context) {
+	c.JSON(http.StatusOK, h.notifier.Deliveries())
 }
@@ -0,0 +1,24 @@
+package restexecutor
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/cluster"
+	"net/http"
+)
+
+// NewFederation 创建查看转发节点状态的接口
+func NewFederation(fed *cluster.Federation) Register {
+	return &federationHandle{fed: fed}
+}
+
+type federationHandle struct {
+	fed *cluster.Federation
+}
+
+func (h *federationHandle) Register(r *gin.Engine) {
+	r.GET("/federation/peers", h.peersGet)
+}
+
+func (h *federationHandle) peersGet(c *gin.Context) {
+	c.JSON(http.StatusOK, h.fed.Peers())
+}
@@ -15,8 +15,15 @@ type fileHandle struct {
 	fs filestore.FileStore
 }
 
+// fileGet 返回租户可以访问的文件
 func (f *fileHandle) fileGet(c *gin.Context) {
 	ids := f.fs.List()
+	t := tenantOf(c)
+	for id := range ids {
+		if !t.CanAccessFile(id) {
+			delete(ids, id)
+		}
+	}
 	c.JSON(http.StatusOK, ids)
 }
 
@@ -26,6 +33,11 @@ func (f *fileHandle) filePost(c *gin.Context) {
 		c.AbortWithError(http.StatusBadRequest, err)
 		return
 	}
+	t := tenantOf(c)
+	if err := t.CheckUpload(fh.Size); err != nil {
+		c.AbortWithStatusJSON(tenantStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
 
 	fi, err := fh.Open()
 	if err != nil {
@@ -46,7 +58,9 @@ func (f *fileHandle) filePost(c *gin.Context) {
 	id, err := f.fs.Add(fh.Filename, sf.Name())
 	if err != nil {
 		c.AbortWithError(http.StatusInternalServerError, err)
+		return
 	}
+	t.AddFile(id)
 	c.JSON(http.StatusOK, id)
 }
 
@@ -60,6 +74,11 @@ func (f *fileHandle) fileIDGet(c *gin.Context) {
 		return
 	}
 
+	// 其他租户的文件视为不存在
+	if !tenantOf(c).CanAccessFile(uri.FileID) {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
 	name, file := f.fs.Get(uri.FileID)
 	if file == nil {
 		c.AbortWithStatus(http.StatusNotFound)
@@ -93,6 +112,10 @@ func (f *fileHandle) fileIDDelete(c *gin.Context) {
 		return
 	}
 
+	if !tenantOf(c).CanAccessFile(uri.FileID) {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
 	ok := f.fs.Remove(uri.FileID)
 	if !ok {
 		c.AbortWithStatus(http.StatusNotFound)
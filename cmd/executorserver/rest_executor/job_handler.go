@@ -0,0 +1,112 @@
+package restexecutor
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/job"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/model"
+	"net/http"
+)
+
+type jobURI struct {
+	JobID string `uri:"id"`
+}
+
+func (h *handle) jobPost(c *gin.Context) {
+	var req model.Request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if len(req.Cmd) == 0 && len(req.Stages) == 0 {
+		c.AbortWithStatusJSON(http.StatusBadRequest, "no cmd provided")
+		return
+	}
+	// 日志中记录展开语言预设之前的请求，重启后按照相同的流程重新转换
+	orig, err := copyRequest(&req)
+	if err != nil {
+		c.Error(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		return
+	}
+	t := tenantOf(c)
+	r, err := h.convertRequest(t, &req)
+	if err != nil {
+		c.Error(err)
+		c.AbortWithStatusJSON(tenantStatus(err, http.StatusBadRequest), err.Error())
+		return
+	}
+	// 租户的名额在任务完成后释放
+	if err := t.Acquire(); err != nil {
+		c.AbortWithStatusJSON(tenantStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+	h.logger.Sugar().Debugf("job request: %+v", r)
+	var owner string
+	if t != nil {
+		owner = t.Name
+	}
+	id, err := h.jobs.Submit(orig, r, owner, t.Release)
+	if err != nil {
+		t.Release(model.Response{})
+	}
+	if errors.Is(err, job.ErrExists) {
+		c.AbortWithStatusJSON(http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		c.AbortWithStatusJSON(errorStatus(err), err.Error())
+		return
+	}
+	c.JSON(http.StatusAccepted, id)
+}
+
+func (h *handle) jobGet(c *gin.Context) {
+	var uri jobURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	// 其他租户的任务视为不存在
+	s, ok := h.jobs.Get(uri.JobID)
+	if !ok || !tenantOf(c).CanAccess(s.Owner) {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	c.JSON(http.StatusOK, s)
+}
+
+func (h *handle) jobDelete(c *gin.Context) {
+	var uri jobURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	if s, ok := h.jobs.Get(uri.JobID); !ok || !tenantOf(c).CanAccess(s.Owner) {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	s, ok := h.jobs.Cancel(uri.JobID)
+	if !ok {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	c.JSON(http.StatusOK, s)
+}
+
+// copyRequest 返回请求的深拷贝
+func copyRequest(req *model.Request) (*model.Request, error) {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	rt := new(model.Request)
+	if err := json.Unmarshal(b, rt); err != nil {
+		return nil, err
+	}
+	return rt, nil
+}
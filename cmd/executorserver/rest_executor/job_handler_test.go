@@ -0,0 +1,74 @@
+package restexecutor
+
+import (
+	"errors"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/language"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/model"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/tenant"
+	"github.com/lxhcaicai/loj-judge/filestore"
+	"testing"
+	"time"
+)
+
+func TestJobConvert(t *testing.T) {
+	catalog, err := language.NewCatalog([]language.Language{{
+		Name:           "java",
+		SourceName:     "Main.java",
+		ExecutableName: "Main.class",
+		Run:            &language.Template{Args: []string{"java", "Main"}, CPULimit: time.Second, MemoryLimit: 256 << 20},
+		ExtraMemory:    64 << 20,
+		TimeFactor:     2,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg, err := tenant.NewRegistry([]tenant.Tenant{
+		{Name: "a", Keys: []string{"a"}, CPULimit: time.Second, Concurrency: 1},
+	}, filestore.NewFileLocalStore(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	convert := NewJobConvert(catalog, nil, reg)
+	newRequest := func(cpuLimit time.Duration) *model.Request {
+		return &model.Request{Cmd: []model.Cmd{{
+			Language: &model.LanguageRef{Name: "java"},
+			CPULimit: uint64(cpuLimit),
+		}}}
+	}
+
+	// 恢复的任务与提交时相同地展开语言预设、设置队列键并占用租户的名额
+	r, done, err := convert(newRequest(0), "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := r.Cmd[0]
+	if r.QueueKey != "a" || len(r.Languages) != 1 || c.CPULimit != 2*time.Second || c.MemoryLimit != 320<<20 {
+		t.Fatalf("unexpected request: queue %q languages %v cpu %v memory %v", r.QueueKey, r.Languages, c.CPULimit, c.MemoryLimit)
+	}
+	a := reg.Tenant("a")
+	if st := a.Status(); st.Running != 1 {
+		t.Fatalf("expected one run acquired, got %d", st.Running)
+	}
+	if _, _, err := convert(newRequest(0), "a"); !errors.Is(err, tenant.ErrTooManyRuns) {
+		t.Fatalf("expected too many runs, got %v", err)
+	}
+	done(model.Response{})
+	if st := a.Status(); st.Running != 0 {
+		t.Fatalf("expected run released, got %d", st.Running)
+	}
+
+	// 租户的限制在语言预设的倍数之前检查
+	if _, _, err := convert(newRequest(2*time.Second), "a"); !errors.Is(err, tenant.ErrLimitExceeded) {
+		t.Fatalf("expected limit exceeded, got %v", err)
+	}
+	if _, _, err := convert(newRequest(0), "removed"); err == nil {
+		t.Fatal("expected error for unknown tenant")
+	}
+
+	// 未启用认证时不做限制
+	r, done, err = NewJobConvert(catalog, nil, nil)(newRequest(0), "a")
+	if err != nil || r.QueueKey != "" {
+		t.Fatalf("unexpected request without tenants: %+v %v", r, err)
+	}
+	done(model.Response{})
+}
@@ -0,0 +1,295 @@
+package restexecutor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/creack/pty"
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
+	"github.com/gorilla/websocket"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/model"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/tenant"
+	"github.com/lxhcaicai/loj-judge/envexec"
+	"github.com/lxhcaicai/loj-judge/worker"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// 客户端发送的消息类型，每个二进制帧的第一个字节表示类型
+const (
+	streamRequest    byte = iota + 1 // JSON 编码的 model.Request，必须是第一帧
+	streamInput                      // 标准输入内容
+	streamResize                     // JSON 编码的 streamResizeMsg
+	streamCancel                     // 取消执行
+	streamInputClose                 // 关闭标准输入
+)
+
+// 服务端发送的消息类型
+const (
+	streamResponse byte = iota + 1 // JSON 编码的 model.Response，为最后一帧
+	streamOutput                   // 第二个字节为文件下标，之后为输出内容
+)
+
+const (
+	streamInputQueue     = 64
+	defaultStreamOutMax  = 64 << 20
+	streamFirstFrameWait = 10 * time.Second
+)
+
+// streamResizeMsg 定义 pty 窗口大小
+type streamResizeMsg struct {
+	Rows uint16 `json:"rows"`
+	Cols uint16 `json:"cols"`
+	X    uint16 `json:"x"`
+	Y    uint16 `json:"y"`
+}
+
+// streamConn 维护单个交互式执行的连接
+type streamConn struct {
+	*handle
+	tenant  *tenant.Tenant
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+
+	inputCh chan []byte
+	stdin   *streamStdin
+}
+
+// streamStdin 将客户端输入通过管道提供给程序，并在 TTY 模式下记录 pty
+type streamStdin struct {
+	*io.PipeReader
+	w *io.PipeWriter
+
+	mu  sync.Mutex
+	pty *os.File
+}
+
+var _ envexec.ReaderTTY = &streamStdin{}
+
+// TTY 在 TTY 启用时由 envexec 调用，用于之后调整窗口大小
+func (s *streamStdin) TTY(f *os.File) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pty = f
+}
+
+func (s *streamStdin) resize(m streamResizeMsg) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pty == nil {
+		return fmt.Errorf("resize: tty is not enabled")
+	}
+	return pty.Setsize(s.pty, &pty.Winsize{Rows: m.Rows, Cols: m.Cols, X: m.X, Y: m.Y})
+}
+
+// streamWriter 将程序输出以帧的形式发送给客户端
+type streamWriter struct {
+	sc    *streamConn
+	index byte
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	b := make([]byte, 0, len(p)+2)
+	b = append(b, streamOutput, w.index)
+	b = append(b, p...)
+	if err := w.sc.write(b); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (h *handle) handleStream(c *gin.Context) {
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	defer conn.Close()
+
+	r, w := io.Pipe()
+	sc := &streamConn{
+		handle:  h,
+		tenant:  tenantOf(c),
+		conn:    conn,
+		inputCh: make(chan []byte, streamInputQueue),
+		stdin:   &streamStdin{PipeReader: r, w: w},
+	}
+	if err := sc.serve(c.Request.Context()); err != nil {
+		h.logger.Sugar().Debug("stream: ", err)
+		sc.writeResponse(model.Response{ErrorMsg: err.Error()})
+	}
+	sc.close()
+}
+
+func (sc *streamConn) serve(baseCtx context.Context) error {
+	req, err := sc.readRequest()
+	if err != nil {
+		return err
+	}
+	if err := sc.tenant.Acquire(); err != nil {
+		return err
+	}
+	var res model.Response
+	defer func() {
+		sc.tenant.Release(res)
+	}()
+	ctx, cancel := context.WithCancel(baseCtx)
+	defer cancel()
+
+	// 将输入写入管道，避免程序不读取输入时阻塞读取循环
+	go sc.inputLoop(ctx)
+	go sc.readLoop(ctx, cancel)
+
+	sc.logger.Sugar().Debugf("stream request: %+v", req)
+	rtCh, _ := sc.worker.Submit(ctx, req)
+	rt := <-rtCh
+	sc.logger.Sugar().Debugf("stream response: %+v", rt)
+
+	if res, err = model.ConvertResponse(rt, false); err != nil {
+		return err
+	}
+	return sc.writeResponse(res)
+}
+
+func (sc *streamConn) readRequest() (*worker.Request, error) {
+	sc.conn.SetReadDeadline(time.Now().Add(streamFirstFrameWait))
+	_, b, err := sc.conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+	sc.conn.SetReadDeadline(time.Time{})
+	if len(b) == 0 || b[0] != streamRequest {
+		return nil, fmt.Errorf("the first frame should be request")
+	}
+
+	var req model.Request
+	if err := json.Unmarshal(b[1:], &req); err != nil {
+		return nil, fmt.Errorf("invalid request: %v", err)
+	}
+	if len(req.Cmd) != 1 {
+		return nil, fmt.Errorf("stream request should have exactly one cmd")
+	}
+
+	// 流式文件无法通过 ConvertRequest 转换，先置空，转换后再替换
+	files := req.Cmd[0].Files
+	req.Cmd[0].Files = make([]*model.CmdFile, len(files))
+	for i, f := range files {
+		if f == nil || !f.StreamIn && !f.StreamOut {
+			req.Cmd[0].Files[i] = f
+		}
+	}
+	r, err := sc.convertRequest(sc.tenant, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	hasInput := false
+	for i, f := range files {
+		switch {
+		case f == nil:
+		case f.StreamIn:
+			if hasInput {
+				return nil, fmt.Errorf("stream request should have at most one streamIn")
+			}
+			hasInput = true
+			r.Cmd[0].Files[i] = &worker.ReaderFile{Reader: sc.stdin, Stream: true}
+		case f.StreamOut:
+			if i > 0xff {
+				return nil, fmt.Errorf("stream out index out of range: %d", i)
+			}
+			max := envexec.Size(defaultStreamOutMax)
+			if f.Max != nil {
+				max = envexec.Size(*f.Max)
+			}
+			r.Cmd[0].Files[i] = &worker.WriterFile{Writer: &streamWriter{sc: sc, index: byte(i)}, Limit: max}
+		}
+	}
+	return r, nil
+}
+
+func (sc *streamConn) readLoop(ctx context.Context, cancel context.CancelFunc) {
+	defer cancel()
+	for {
+		_, b, err := sc.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if len(b) == 0 {
+			continue
+		}
+		switch b[0] {
+		case streamInput:
+			select {
+			case sc.inputCh <- b[1:]:
+			case <-ctx.Done():
+				return
+			}
+		case streamInputClose:
+			select {
+			case sc.inputCh <- nil:
+			case <-ctx.Done():
+				return
+			}
+		case streamResize:
+			var m streamResizeMsg
+			if err := json.Unmarshal(b[1:], &m); err != nil {
+				sc.logger.Sugar().Debug("stream resize: ", err)
+				continue
+			}
+			if err := sc.stdin.resize(m); err != nil {
+				sc.logger.Sugar().Debug("stream resize: ", err)
+			}
+		case streamCancel:
+			return
+		default:
+			sc.logger.Sugar().Debug("stream: unknown frame type ", b[0])
+		}
+	}
+}
+
+func (sc *streamConn) inputLoop(ctx context.Context) {
+	for {
+		select {
+		case b := <-sc.inputCh:
+			// nil 表示客户端关闭了标准输入
+			if b == nil {
+				sc.stdin.w.Close()
+				return
+			}
+			if _, err := sc.stdin.w.Write(b); err != nil {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (sc *streamConn) writeResponse(res model.Response) error {
+	b, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	return sc.write(append([]byte{streamResponse}, b...))
+}
+
+func (sc *streamConn) write(b []byte) error {
+	sc.writeMu.Lock()
+	defer sc.writeMu.Unlock()
+
+	sc.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return sc.conn.WriteMessage(websocket.BinaryMessage, b)
+}
+
+func (sc *streamConn) close() {
+	// 关闭标准输入，结束 envexec 中的复制
+	sc.stdin.w.CloseWithError(errors.New("stream closed"))
+
+	sc.writeMu.Lock()
+	defer sc.writeMu.Unlock()
+	sc.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	sc.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+}
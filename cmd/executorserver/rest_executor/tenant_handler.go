@@ -0,0 +1,74 @@
+package restexecutor
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/tenant"
+	"net/http"
+	"strings"
+)
+
+const tenantContextKey = "tenant"
+
+// 只有管理员租户可以访问的接口
+var adminPrefixes = []string{"/admin", "/cluster", "/federation", "/webhooks"}
+
+// Authenticate 通过 Authorization: Bearer <api key> 认证租户，
+// 管理以及集群接口只允许管理员租户访问
+func Authenticate(reg *tenant.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok {
+			c.Header("WWW-Authenticate", "Bearer")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, "api key not provided")
+			return
+		}
+		t := reg.Lookup(strings.TrimSpace(key))
+		if t == nil {
+			c.Header("WWW-Authenticate", "Bearer")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, "invalid api key")
+			return
+		}
+		if !t.Admin {
+			for _, p := range adminPrefixes {
+				if strings.HasPrefix(c.Request.URL.Path, p) {
+					c.AbortWithStatusJSON(http.StatusForbidden, "admin api key required")
+					return
+				}
+			}
+		}
+		c.Set(tenantContextKey, t)
+		c.Next()
+	}
+}
+
+// tenantOf 返回请求认证的租户，未启用认证时返回 nil
+func tenantOf(c *gin.Context) *tenant.Tenant {
+	t, _ := c.Get(tenantContextKey)
+	rt, _ := t.(*tenant.Tenant)
+	return rt
+}
+
+// tenantStatus 超过租户限制或者引用本地文件时返回 403，并发执行数量达到上限时返回 429，
+// 文件存储配额已满时返回 413，其他错误返回 status
+func tenantStatus(err error, status int) int {
+	switch {
+	case errors.Is(err, tenant.ErrLimitExceeded), errors.Is(err, tenant.ErrLocalFile):
+		return http.StatusForbidden
+	case errors.Is(err, tenant.ErrTooManyRuns):
+		return http.StatusTooManyRequests
+	case errors.Is(err, tenant.ErrQuotaExceeded):
+		return http.StatusRequestEntityTooLarge
+	}
+	return status
+}
+
+// tenantGet 返回当前租户的配额以及用量
+func (h *handle) tenantGet(c *gin.Context) {
+	t := tenantOf(c)
+	if t == nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, "authentication not enabled")
+		return
+	}
+	c.JSON(http.StatusOK, t.Status())
+}
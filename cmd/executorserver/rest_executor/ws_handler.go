@@ -0,0 +1,238 @@
+package restexecutor
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
+	"github.com/gorilla/websocket"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/model"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/tenant"
+	"sync"
+	"time"
+)
+
+const (
+	wsWriteWait  = 10 * time.Second
+	wsPongWait   = 60 * time.Second
+	wsPingPeriod = wsPongWait * 9 / 10
+	wsMaxMessage = 64 << 20
+	wsWriteQueue = 64
+)
+
+// upgrader 使用默认的同源检查，浏览器中其他网站的页面不能连接 /ws 以及 /stream，
+// 没有 Origin 头的非浏览器客户端不受影响
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1 << 10,
+	WriteBufferSize: 1 << 10,
+}
+
+// wsRequest 定义 websocket 连接上收到的帧，
+// 可以是一个新的请求或者取消一个正在执行的请求
+type wsRequest struct {
+	model.Request
+	CancelRequestID *string `json:"cancelRequestId"`
+}
+
+// wsConn 维护单个 websocket 连接上正在执行的请求
+type wsConn struct {
+	*handle
+	tenant  *tenant.Tenant
+	conn    *websocket.Conn
+	writeCh chan model.Response
+
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func (h *handle) handleWS(c *gin.Context) {
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	ws := &wsConn{
+		handle:  h,
+		tenant:  tenantOf(c),
+		conn:    conn,
+		writeCh: make(chan model.Response, wsWriteQueue),
+		cancels: make(map[string]context.CancelFunc),
+	}
+	ws.serve(c.Request.Context())
+}
+
+func (ws *wsConn) serve(baseCtx context.Context) {
+	ctx, cancel := context.WithCancel(baseCtx)
+	defer cancel()
+
+	writeDone := make(chan struct{})
+	go func() {
+		defer close(writeDone)
+		ws.writeLoop()
+	}()
+
+	ws.readLoop(ctx)
+
+	// 连接断开后取消所有请求，等待它们返回后再关闭写通道
+	cancel()
+	ws.wg.Wait()
+	close(ws.writeCh)
+	<-writeDone
+	ws.conn.Close()
+}
+
+func (ws *wsConn) readLoop(ctx context.Context) {
+	ws.conn.SetReadLimit(wsMaxMessage)
+	ws.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	ws.conn.SetPongHandler(func(string) error {
+		ws.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		return nil
+	})
+
+	for {
+		_, b, err := ws.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				ws.logger.Sugar().Info("ws read error: ", err)
+			}
+			return
+		}
+		var req wsRequest
+		if err := json.Unmarshal(b, &req); err != nil {
+			ws.send(ctx, model.Response{ErrorMsg: fmt.Sprintf("invalid request: %v", err)})
+			continue
+		}
+		if req.CancelRequestID != nil {
+			ws.cancel(*req.CancelRequestID)
+			continue
+		}
+		ws.submit(ctx, &req.Request)
+	}
+}
+
+func (ws *wsConn) writeLoop() {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case res, ok := <-ws.writeCh:
+			if !ok {
+				ws.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+				ws.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+			b, err := json.Marshal(res)
+			if err != nil {
+				ws.logger.Sugar().Error("ws encode response: ", err)
+				continue
+			}
+			ws.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := ws.conn.WriteMessage(websocket.TextMessage, b); err != nil {
+				ws.logger.Sugar().Info("ws write error: ", err)
+				ws.conn.Close()
+				ws.drain()
+				return
+			}
+		case <-ticker.C:
+			ws.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := ws.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				ws.conn.Close()
+				ws.drain()
+				return
+			}
+		}
+	}
+}
+
+// drain 在写入失败后丢弃剩余的响应，避免执行中的请求阻塞
+func (ws *wsConn) drain() {
+	for range ws.writeCh {
+	}
+}
+
+func (ws *wsConn) submit(ctx context.Context, req *model.Request) {
+	if len(req.Cmd) == 0 && len(req.Stages) == 0 {
+		ws.send(ctx, model.Response{RequestID: req.RequestID, ErrorMsg: "no cmd provided"})
+		return
+	}
+	// 未提供 requestId 的请求由服务端生成，客户端通过响应中的 requestId 对应请求
+	if req.RequestID == "" {
+		id, err := generateRequestID()
+		if err != nil {
+			ws.send(ctx, model.Response{ErrorMsg: err.Error()})
+			return
+		}
+		req.RequestID = id
+	}
+	r, err := ws.convertRequest(ws.tenant, req)
+	if err != nil {
+		ws.send(ctx, model.Response{RequestID: req.RequestID, ErrorMsg: err.Error()})
+		return
+	}
+	if err := ws.tenant.Acquire(); err != nil {
+		ws.send(ctx, model.Response{RequestID: req.RequestID, ErrorMsg: err.Error()})
+		return
+	}
+
+	ws.mu.Lock()
+	if _, ok := ws.cancels[req.RequestID]; ok {
+		ws.mu.Unlock()
+		ws.tenant.Release(model.Response{})
+		ws.send(ctx, model.Response{RequestID: req.RequestID, ErrorMsg: "duplicated requestId"})
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	ws.cancels[req.RequestID] = cancel
+	ws.mu.Unlock()
+
+	ws.wg.Add(1)
+	go func() {
+		defer ws.wg.Done()
+
+		ws.logger.Sugar().Debugf("ws request: %+v", r)
+		rtCh, _ := ws.worker.Submit(ctx, r)
+		rt := <-rtCh
+		ws.logger.Sugar().Debugf("ws response: %+v", rt)
+
+		ws.mu.Lock()
+		delete(ws.cancels, req.RequestID)
+		ws.mu.Unlock()
+		cancel()
+
+		res, err := model.ConvertResponse(rt, false)
+		if err != nil {
+			res = model.Response{RequestID: req.RequestID, ErrorMsg: err.Error()}
+		}
+		ws.tenant.Release(res)
+		ws.notifier.Notify(req.Callback, res)
+		ws.writeCh <- res
+	}()
+}
+
+func (ws *wsConn) cancel(requestID string) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	if cancel, ok := ws.cancels[requestID]; ok {
+		cancel()
+	}
+}
+
+func (ws *wsConn) send(ctx context.Context, res model.Response) {
+	select {
+	case ws.writeCh <- res:
+	case <-ctx.Done():
+	}
+}
+
+func generateRequestID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "ws-" + hex.EncodeToString(b), nil
+}
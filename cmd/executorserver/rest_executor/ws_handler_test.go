@@ -0,0 +1,36 @@
+package restexecutor
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWSCrossOrigin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	New(nil, nil, nil, nil, nil, nil, zap.NewNop()).Register(r)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	base := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	for _, path := range []string{"/ws", "/stream"} {
+		// 其他网站的页面发起的连接被拒绝
+		_, resp, err := websocket.DefaultDialer.Dial(base+path, http.Header{"Origin": {"http://evil.example"}})
+		if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+			t.Fatalf("%s: expected cross origin connection rejected, got %v", path, err)
+		}
+		// 同源以及没有 Origin 的客户端可以连接
+		for _, h := range []http.Header{nil, {"Origin": {srv.URL}}} {
+			c, _, err := websocket.DefaultDialer.Dial(base+path, h)
+			if err != nil {
+				t.Fatalf("%s: expected connection accepted with %v, got %v", path, h, err)
+			}
+			c.Close()
+		}
+	}
+}
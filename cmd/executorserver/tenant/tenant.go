@@ -0,0 +1,375 @@
+package tenant
+
+import (
+	"errors"
+	"fmt"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/model"
+	"github.com/lxhcaicai/loj-judge/envexec"
+	"github.com/lxhcaicai/loj-judge/filestore"
+	"github.com/lxhcaicai/loj-judge/worker"
+	"gopkg.in/yaml.v2"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrLimitExceeded 表示请求的限制超过了租户的上限
+	ErrLimitExceeded = errors.New("tenant limit exceeded")
+	// ErrTooManyRuns 表示租户同时执行的请求数量已经达到上限
+	ErrTooManyRuns = errors.New("too many concurrent runs")
+	// ErrQuotaExceeded 表示租户在文件存储中占用的空间已经达到配额
+	ErrQuotaExceeded = errors.New("file store quota exceeded")
+	// ErrFileNotExists 表示请求引用的缓存文件不存在或者属于其他租户
+	ErrFileNotExists = errors.New("file not exists")
+	// ErrLocalFile 表示非管理员租户的请求引用了服务器上的本地文件
+	ErrLocalFile = errors.New("local file not allowed")
+)
+
+// Size 在 yaml 中可以使用 256m 等形式表示的字节数
+type Size uint64
+
+// UnmarshalYAML 解析数字或者带单位的大小
+func (s *Size) UnmarshalYAML(unmarshal func(any) error) error {
+	var str string
+	if err := unmarshal(&str); err != nil {
+		return err
+	}
+	var v envexec.Size
+	if err := v.Set(str); err != nil {
+		return err
+	}
+	*s = Size(v)
+	return nil
+}
+
+// Tenant 定义租户的 api key 以及配额，为 0 的限制不生效。
+// 租户只能访问自己创建的文件以及任务，只有管理员租户可以使用服务器上的本地文件 (src)，文件的归属只保存在内存中，重启之前创建的文件只有管理员租户可以访问
+type Tenant struct {
+	Name  string   `yaml:"name" json:"name"`
+	Keys  []string `yaml:"keys" json:"-"`
+	Admin bool     `yaml:"admin" json:"admin,omitempty"` // 允许访问管理以及集群接口，以及所有租户的文件和任务
+
+	// 单个命令的限制上限，请求未设置的限制使用上限
+	CPULimit    time.Duration `yaml:"cpuLimit" json:"cpuLimit,omitempty"`
+	ClockLimit  time.Duration `yaml:"clockLimit" json:"clockLimit,omitempty"`
+	MemoryLimit Size          `yaml:"memoryLimit" json:"memoryLimit,omitempty"`
+	ProcLimit   uint64        `yaml:"procLimit" json:"procLimit,omitempty"`
+
+	CmdLimit    int  `yaml:"cmdLimit" json:"cmdLimit,omitempty"`       // 单个请求中所有阶段的命令数量之和
+	Concurrency int  `yaml:"concurrency" json:"concurrency,omitempty"` // 同时执行的请求数量
+	FileQuota   Size `yaml:"fileQuota" json:"fileQuota,omitempty"`     // 在文件存储中占用的字节数
+
+	usage *usage
+}
+
+// Status 定义租户的配额以及当前用量
+type Status struct {
+	*Tenant
+	Running   int   `json:"running"`
+	FileUsage int64 `json:"fileUsage"`
+}
+
+type usage struct {
+	fs filestore.FileStore
+
+	mu      sync.Mutex
+	running int
+	files   map[string]int64 // 租户创建的文件 id -> 大小
+}
+
+type tenants struct {
+	Tenants []Tenant `yaml:"tenants"`
+}
+
+// Registry 通过 api key 或者名称查找租户
+type Registry struct {
+	keys  map[string]*Tenant
+	names map[string]*Tenant
+}
+
+// ReadRegistry 从 yaml 文件读取租户配置
+func ReadRegistry(p string, fs filestore.FileStore) (*Registry, error) {
+	d, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	var t tenants
+	if err := yaml.UnmarshalStrict(d, &t); err != nil {
+		return nil, err
+	}
+	return NewRegistry(t.Tenants, fs)
+}
+
+// NewRegistry 创建租户注册表，租户名称以及 api key 不能重复
+func NewRegistry(ts []Tenant, fs filestore.FileStore) (*Registry, error) {
+	r := &Registry{keys: make(map[string]*Tenant), names: make(map[string]*Tenant, len(ts))}
+	for i := range ts {
+		t := &ts[i]
+		if t.Name == "" {
+			return nil, fmt.Errorf("tenant #%d: name not provided", i)
+		}
+		if _, ok := r.names[t.Name]; ok {
+			return nil, fmt.Errorf("tenant %s: defined more than once", t.Name)
+		}
+		r.names[t.Name] = t
+		if len(t.Keys) == 0 {
+			return nil, fmt.Errorf("tenant %s: keys not provided", t.Name)
+		}
+		for _, k := range t.Keys {
+			if k == "" {
+				return nil, fmt.Errorf("tenant %s: empty key", t.Name)
+			}
+			if _, ok := r.keys[k]; ok {
+				return nil, fmt.Errorf("tenant %s: key used by more than one tenant", t.Name)
+			}
+			r.keys[k] = t
+		}
+		t.usage = &usage{fs: fs, files: make(map[string]int64)}
+	}
+	return r, nil
+}
+
+// Lookup 返回 api key 对应的租户，不存在时返回 nil
+func (r *Registry) Lookup(key string) *Tenant {
+	return r.keys[key]
+}
+
+// Tenant 返回名称对应的租户，不存在时返回 nil
+func (r *Registry) Tenant(name string) *Tenant {
+	return r.names[name]
+}
+
+// Check 检查并补全请求的限制，超过上限时返回 ErrLimitExceeded。
+// 请求需要缓存输出文件而文件存储配额已满时返回 ErrQuotaExceeded，
+// 引用了其他租户的缓存文件时返回 ErrFileNotExists，非管理员租户引用本地文件时返回 ErrLocalFile。
+// t 为 nil (未启用认证) 时不做任何限制
+func (t *Tenant) Check(r *worker.Request) error {
+	if t == nil {
+		return nil
+	}
+	if n := countCmds(r); t.CmdLimit > 0 && n > t.CmdLimit {
+		return fmt.Errorf("%w: %d commands exceeds %d", ErrLimitExceeded, n, t.CmdLimit)
+	}
+	copyOut := false
+	var check func(name string, c *worker.Cmd) error
+	check = func(name string, c *worker.Cmd) error {
+		if err := t.checkFiles(c); err != nil {
+			return err
+		}
+		if err := limit(name, "cpuLimit", &c.CPULimit, t.CPULimit); err != nil {
+			return err
+		}
+		if err := limit(name, "clockLimit", &c.ClockLimit, t.ClockLimit); err != nil {
+			return err
+		}
+		if err := limit(name, "memoryLimit", &c.MemoryLimit, worker.Size(t.MemoryLimit)); err != nil {
+			return err
+		}
+		if err := limit(name, "procLimit", &c.ProcLimit, t.ProcLimit); err != nil {
+			return err
+		}
+		if len(c.CopyOutCached) > 0 {
+			copyOut = true
+		}
+		if c.Checker != nil {
+			return check(name+" checker", &c.Checker.Cmd)
+		}
+		return nil
+	}
+	for i := range r.Cmd {
+		if err := check(fmt.Sprintf("cmd %d", i), &r.Cmd[i]); err != nil {
+			return err
+		}
+	}
+	for i, s := range r.Stages {
+		for j := range s.Cmd {
+			if err := check(fmt.Sprintf("stage %d cmd %d", i, j), &s.Cmd[j]); err != nil {
+				return err
+			}
+		}
+	}
+	if copyOut {
+		return t.checkQuota(0)
+	}
+	return nil
+}
+
+// countCmds 返回请求中所有阶段的命令数量，checker 不单独计入
+func countCmds(r *worker.Request) int {
+	n := len(r.Cmd)
+	for _, s := range r.Stages {
+		n += len(s.Cmd)
+	}
+	return n
+}
+
+// checkFiles 检查命令引用的缓存文件以及本地文件是否可以访问
+func (t *Tenant) checkFiles(c *worker.Cmd) error {
+	files := append([]worker.CmdFile(nil), c.Files...)
+	for _, f := range c.CopyIn {
+		files = append(files, f)
+	}
+	if c.Compare != nil {
+		files = append(files, c.Compare.Answer)
+	}
+	if c.Checker != nil {
+		files = append(files, c.Checker.Input, c.Checker.Answer)
+	}
+	for _, f := range files {
+		switch f := f.(type) {
+		case *worker.CachedFile:
+			if !t.CanAccessFile(f.FileID) {
+				return fmt.Errorf("%w with id %v", ErrFileNotExists, f.FileID)
+			}
+		case *worker.LocalFile:
+			// 本地文件可以读取服务器上的任意文件，包括文件存储中其他租户的文件
+			if !t.Admin {
+				return fmt.Errorf("%w: %s", ErrLocalFile, f.Src)
+			}
+		}
+	}
+	return nil
+}
+
+// limit 将未设置的限制设置为上限，超过上限时返回错误
+func limit[T time.Duration | worker.Size | uint64](name, field string, v *T, max T) error {
+	switch {
+	case max == 0:
+	case *v == 0:
+		*v = max
+	case *v > max:
+		return fmt.Errorf("%w: %s %s %v exceeds %v", ErrLimitExceeded, name, field, *v, max)
+	}
+	return nil
+}
+
+// Acquire 占用一个同时执行的名额，达到上限时返回 ErrTooManyRuns
+func (t *Tenant) Acquire() error {
+	if t == nil {
+		return nil
+	}
+	t.usage.mu.Lock()
+	defer t.usage.mu.Unlock()
+
+	if t.Concurrency > 0 && t.usage.running >= t.Concurrency {
+		return fmt.Errorf("%w: limit is %d", ErrTooManyRuns, t.Concurrency)
+	}
+	t.usage.running++
+	return nil
+}
+
+// Release 释放 Acquire 占用的名额，并将结果中的缓存文件计入文件存储用量
+func (t *Tenant) Release(res model.Response) {
+	if t == nil {
+		return
+	}
+	t.usage.mu.Lock()
+	defer t.usage.mu.Unlock()
+
+	t.usage.running--
+	add := func(results []model.Result) {
+		for _, r := range results {
+			for _, id := range r.FileIDs {
+				t.usage.add(id)
+			}
+		}
+	}
+	add(res.Results)
+	for _, s := range res.Stages {
+		add(s.Results)
+	}
+}
+
+// CheckUpload 检查上传 size 字节的文件是否超过文件存储配额
+func (t *Tenant) CheckUpload(size int64) error {
+	if t == nil {
+		return nil
+	}
+	return t.checkQuota(size)
+}
+
+// AddFile 将租户上传的文件计入文件存储用量
+func (t *Tenant) AddFile(id string) {
+	if t == nil {
+		return
+	}
+	t.usage.mu.Lock()
+	defer t.usage.mu.Unlock()
+
+	t.usage.add(id)
+}
+
+// CanAccess 返回租户是否可以访问 owner 创建的任务，t 为 nil (未启用认证) 时返回 true
+func (t *Tenant) CanAccess(owner string) bool {
+	return t == nil || t.Admin || t.Name == owner
+}
+
+// CanAccessFile 返回租户是否可以访问文件存储中的文件，t 为 nil (未启用认证) 时返回 true
+func (t *Tenant) CanAccessFile(id string) bool {
+	if t == nil || t.Admin {
+		return true
+	}
+	t.usage.mu.Lock()
+	defer t.usage.mu.Unlock()
+
+	_, ok := t.usage.files[id]
+	return ok
+}
+
+// Status 返回租户的配额以及当前用量
+func (t *Tenant) Status() Status {
+	t.usage.mu.Lock()
+	defer t.usage.mu.Unlock()
+
+	return Status{Tenant: t, Running: t.usage.running, FileUsage: t.usage.total()}
+}
+
+func (t *Tenant) checkQuota(size int64) error {
+	if t.FileQuota == 0 {
+		return nil
+	}
+	t.usage.mu.Lock()
+	defer t.usage.mu.Unlock()
+
+	used := t.usage.total()
+	if used >= int64(t.FileQuota) || used+size > int64(t.FileQuota) {
+		return fmt.Errorf("%w: %d of %d bytes used", ErrQuotaExceeded, used, t.FileQuota)
+	}
+	return nil
+}
+
+// add 记录文件的大小，调用时需要持有锁
+func (u *usage) add(id string) {
+	_, f := u.fs.Get(id)
+	if f == nil {
+		return
+	}
+	u.files[id] = fileSize(f)
+}
+
+// total 返回仍然存在的文件的大小之和，已经被删除或者过期的文件不再计入。调用时需要持有锁
+func (u *usage) total() int64 {
+	if len(u.files) == 0 {
+		return 0
+	}
+	exists := u.fs.List()
+	var rt int64
+	for id, size := range u.files {
+		if _, ok := exists[id]; !ok {
+			delete(u.files, id)
+			continue
+		}
+		rt += size
+	}
+	return rt
+}
+
+func fileSize(f envexec.File) int64 {
+	if f, ok := f.(*envexec.FileInput); ok {
+		if fi, err := os.Stat(f.Path); err == nil {
+			return fi.Size()
+		}
+	}
+	return 0
+}
@@ -0,0 +1,79 @@
+package tenant
+
+import (
+	"errors"
+	"github.com/lxhcaicai/loj-judge/filestore"
+	"github.com/lxhcaicai/loj-judge/worker"
+	"testing"
+	"time"
+)
+
+func newTestRegistry(t *testing.T, ts ...Tenant) *Registry {
+	t.Helper()
+	r, err := NewRegistry(ts, filestore.NewFileLocalStore(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestCheckFiles(t *testing.T) {
+	r := newTestRegistry(t,
+		Tenant{Name: "a", Keys: []string{"a"}},
+		Tenant{Name: "admin", Keys: []string{"admin"}, Admin: true},
+	)
+	local := func() *worker.Request {
+		return &worker.Request{Cmd: []worker.Cmd{{
+			Args:   []string{"cat", "x"},
+			CopyIn: map[string]worker.CmdFile{"x": &worker.LocalFile{Src: "/etc/passwd"}},
+		}}}
+	}
+	cached := func() *worker.Request {
+		return &worker.Request{Cmd: []worker.Cmd{{
+			Args:    []string{"cat"},
+			Compare: &worker.Compare{Answer: &worker.CachedFile{FileID: "other"}},
+		}}}
+	}
+
+	for _, tc := range []struct {
+		key string
+		r   *worker.Request
+		err error
+	}{
+		{"a", local(), ErrLocalFile},
+		{"admin", local(), nil},
+		{"a", cached(), ErrFileNotExists},
+		{"admin", cached(), nil},
+	} {
+		if err := r.Lookup(tc.key).Check(tc.r); !errors.Is(err, tc.err) {
+			t.Errorf("%s: expected %v, got %v", tc.key, tc.err, err)
+		}
+	}
+}
+
+func TestCheckLimits(t *testing.T) {
+	r := newTestRegistry(t, Tenant{Name: "a", Keys: []string{"a"}, ClockLimit: time.Second, CmdLimit: 2})
+	a := r.Lookup("a")
+
+	// 未设置的限制使用上限
+	req := &worker.Request{Cmd: []worker.Cmd{{Args: []string{"a"}}}}
+	if err := a.Check(req); err != nil || req.Cmd[0].ClockLimit != time.Second {
+		t.Fatalf("expected clock limit set to the cap, got %v %v", req.Cmd[0].ClockLimit, err)
+	}
+
+	for name, req := range map[string]*worker.Request{
+		"clock limit": {Cmd: []worker.Cmd{{Args: []string{"a"}, ClockLimit: time.Minute}}},
+		"checker clock limit": {Cmd: []worker.Cmd{{
+			Args:    []string{"a"},
+			Checker: &worker.Checker{Cmd: worker.Cmd{Args: []string{"chk"}, ClockLimit: time.Minute}},
+		}}},
+		"command count": {Stages: []worker.Stage{
+			{Cmd: []worker.Cmd{{Args: []string{"a"}}, {Args: []string{"b"}}}},
+			{Cmd: []worker.Cmd{{Args: []string{"c"}}}},
+		}},
+	} {
+		if err := a.Check(req); !errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("%s: expected limit exceeded, got %v", name, err)
+		}
+	}
+}
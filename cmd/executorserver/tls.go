@@ -0,0 +1,142 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// certReloader 保存当前的证书以及客户端 CA，文件修改后重新加载，加载失败时继续使用之前的证书
+type certReloader struct {
+	certFile, keyFile, caFile string
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	clientCA *x509.CertPool
+	modTimes []time.Time
+}
+
+// newTLSConfig 在配置了证书时创建 TLS 配置，设置了客户端 CA 时要求并验证客户端证书
+func newTLSConfig(certFile, keyFile, caFile string, interval time.Duration) (*tls.Config, error) {
+	if certFile == "" && keyFile == "" {
+		if caFile != "" {
+			return nil, errors.New("client CA requires certificate and key")
+		}
+		return nil, nil
+	}
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("both certificate and key should be provided")
+	}
+	r := &certReloader{certFile: certFile, keyFile: keyFile, caFile: caFile}
+	r.modTimes = r.stat()
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	go r.watch(interval)
+
+	c := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.getCertificate,
+	}
+	if caFile != "" {
+		// 由 verifyClient 使用当前的客户端 CA 验证，以便 CA 文件可以重新加载。
+		// VerifyConnection 在会话恢复时同样被调用，VerifyPeerCertificate 则不会
+		c.ClientAuth = tls.RequireAnyClientCert
+		c.VerifyConnection = r.verifyClient
+	}
+	return c, nil
+}
+
+func (r *certReloader) load() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load certificate: %w", err)
+	}
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		b, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return fmt.Errorf("load client CA: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return fmt.Errorf("load client CA: no certificate found in %s", r.caFile)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert, r.clientCA = &cert, pool
+	return nil
+}
+
+// stat 返回证书文件的修改时间，文件不存在时为零值
+func (r *certReloader) stat() []time.Time {
+	files := []string{r.certFile, r.keyFile, r.caFile}
+	rt := make([]time.Time, len(files))
+	for i, f := range files {
+		if f == "" {
+			continue
+		}
+		if fi, err := os.Stat(f); err == nil {
+			rt[i] = fi.ModTime()
+		}
+	}
+	return rt
+}
+
+// watch 定期检查证书文件，修改后重新加载
+func (r *certReloader) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		modTimes := r.stat()
+		changed := false
+		for i := range modTimes {
+			if !modTimes[i].Equal(r.modTimes[i]) {
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		// 证书与私钥可能不是同时写入，加载失败时等待下一次修改
+		r.modTimes = modTimes
+		if err := r.load(); err != nil {
+			logger.Sugar().Warn("Reload TLS certificate failed, keep using the previous one: ", err)
+			continue
+		}
+		logger.Sugar().Info("TLS certificate reloaded")
+	}
+}
+
+func (r *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+func (r *certReloader) verifyClient(cs tls.ConnectionState) error {
+	certs := cs.PeerCertificates
+	if len(certs) == 0 {
+		return errors.New("client certificate required")
+	}
+	r.mu.RLock()
+	roots := r.clientCA
+	r.mu.RUnlock()
+
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	for _, c := range certs[1:] {
+		opts.Intermediates.AddCert(c)
+	}
+	_, err := certs[0].Verify(opts)
+	return err
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"go.uber.org/zap"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newCert 创建由 parent 签名的证书，parent 为 nil 时创建自签名的 CA
+func newCert(t *testing.T, name string, parent *tls.Certificate, usage x509.ExtKeyUsage) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	signer, signerKey := tmpl, any(key)
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func writePEM(t *testing.T, path string, c tls.Certificate, withKey bool) {
+	t.Helper()
+	b := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Certificate[0]})
+	if withKey {
+		der, err := x509.MarshalECPrivateKey(c.PrivateKey.(*ecdsa.PrivateKey))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b = append(b, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})...)
+	}
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestClientCARotationWithResumption 检查恢复的会话同样使用当前的客户端 CA 验证
+func TestClientCARotationWithResumption(t *testing.T) {
+	logger = zap.NewNop()
+	dir := t.TempDir()
+	certFile, caFile := filepath.Join(dir, "server.pem"), filepath.Join(dir, "ca.pem")
+	writePEM(t, certFile, newCert(t, "server", nil, x509.ExtKeyUsageServerAuth), true)
+	ca := newCert(t, "ca", nil, x509.ExtKeyUsageClientAuth)
+	writePEM(t, caFile, ca, false)
+	client := newCert(t, "client", &ca, x509.ExtKeyUsageClientAuth)
+
+	conf, err := newTLSConfig(certFile, certFile, caFile, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis, err := tls.Listen("tcp", "127.0.0.1:0", conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	go func() {
+		for {
+			c, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				if c.(*tls.Conn).Handshake() == nil {
+					c.Write([]byte("ok"))
+				}
+			}()
+		}
+	}()
+
+	clientConf := &tls.Config{
+		Certificates:       []tls.Certificate{client},
+		InsecureSkipVerify: true,
+		ClientSessionCache: tls.NewLRUClientSessionCache(1),
+	}
+	// dial 返回服务器是否接受连接以及会话是否恢复
+	dial := func() (bool, bool) {
+		c, err := tls.DialWithDialer(&net.Dialer{Timeout: time.Second}, "tcp", lis.Addr().String(), clientConf)
+		if err != nil {
+			return false, false
+		}
+		defer c.Close()
+		b, _ := io.ReadAll(c)
+		return string(b) == "ok", c.ConnectionState().DidResume
+	}
+
+	if ok, _ := dial(); !ok {
+		t.Fatal("expected client certificate accepted")
+	}
+	if ok, resumed := dial(); !ok || !resumed {
+		t.Fatalf("expected resumed session accepted, ok %v resumed %v", ok, resumed)
+	}
+
+	// 更换客户端 CA 后，之前的会话恢复也需要被拒绝
+	time.Sleep(20 * time.Millisecond)
+	writePEM(t, caFile, newCert(t, "other", nil, x509.ExtKeyUsageClientAuth), false)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		ok, _ := dial()
+		if !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected client certificate rejected after the client CA is rotated")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,234 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/goccy/go-json"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/model"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// SignatureHeader 为请求体的 HMAC-SHA256 签名，格式为 sha256=<hex>
+	SignatureHeader = "X-Signature-256"
+	// DeliveryHeader 为投递 id，重试时保持不变
+	DeliveryHeader = "X-Delivery-ID"
+)
+
+// Config 定义回调投递配置
+type Config struct {
+	Client     *http.Client
+	MaxRetry   int           // 失败后最多重试次数
+	Backoff    time.Duration // 第一次重试前的等待时间，之后每次翻倍
+	MaxBackoff time.Duration
+	LogSize    int // 保留的投递记录数量
+	Logger     *zap.Logger
+}
+
+// Delivery 记录一次回调投递的结果
+type Delivery struct {
+	ID         string     `json:"id"`
+	RequestID  string     `json:"requestId"`
+	URL        string     `json:"url"`
+	Attempts   int        `json:"attempts"`
+	StatusCode int        `json:"statusCode,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	Success    bool       `json:"success"`
+	CreateTime time.Time  `json:"createTime"`
+	FinishTime *time.Time `json:"finishTime,omitempty"`
+}
+
+// Notifier 将完成的结果投递到请求中的回调地址
+type Notifier struct {
+	conf Config
+
+	mu   sync.Mutex
+	log  []*Delivery
+	next int
+	wg   sync.WaitGroup
+	done chan struct{}
+	once sync.Once
+}
+
+// New 创建回调投递器
+func New(conf Config) *Notifier {
+	if conf.Client == nil {
+		conf.Client = http.DefaultClient
+	}
+	if conf.Backoff <= 0 {
+		conf.Backoff = time.Second
+	}
+	if conf.MaxBackoff < conf.Backoff {
+		conf.MaxBackoff = conf.Backoff << 5
+	}
+	if conf.LogSize <= 0 {
+		conf.LogSize = 1
+	}
+	if conf.Logger == nil {
+		conf.Logger = zap.NewNop()
+	}
+	return &Notifier{
+		conf: conf,
+		log:  make([]*Delivery, 0, conf.LogSize),
+		done: make(chan struct{}),
+	}
+}
+
+// Notify 在后台将结果以 JSON 形式投递到回调地址，cb 为空时不做任何事
+func (n *Notifier) Notify(cb *model.Callback, res model.Response) {
+	if cb == nil || cb.URL == "" {
+		return
+	}
+	body, err := json.Marshal(res)
+	if err != nil {
+		n.conf.Logger.Sugar().Error("webhook encode response: ", err)
+		return
+	}
+	id, err := generateID()
+	if err != nil {
+		n.conf.Logger.Sugar().Error("webhook generate id: ", err)
+		return
+	}
+	d := &Delivery{
+		ID:         id,
+		RequestID:  res.RequestID,
+		URL:        cb.URL,
+		CreateTime: time.Now(),
+	}
+	n.record(d)
+
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		n.deliver(d, cb, body)
+	}()
+}
+
+// Deliveries 返回最近的投递记录，从新到旧
+func (n *Notifier) Deliveries() []Delivery {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	rt := make([]Delivery, 0, len(n.log))
+	for i := 1; i <= len(n.log); i++ {
+		rt = append(rt, *n.log[(n.next-i+len(n.log))%len(n.log)])
+	}
+	return rt
+}
+
+// Shutdown 停止重试并等待正在进行的投递完成
+func (n *Notifier) Shutdown(ctx context.Context) error {
+	n.once.Do(func() {
+		close(n.done)
+	})
+	finished := make(chan struct{})
+	go func() {
+		n.wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (n *Notifier) deliver(d *Delivery, cb *model.Callback, body []byte) {
+	backoff := n.conf.Backoff
+	for attempt := 1; ; attempt++ {
+		code, err := n.post(d.ID, cb, body)
+
+		n.mu.Lock()
+		d.Attempts = attempt
+		d.StatusCode = code
+		d.Error = ""
+		if err != nil {
+			d.Error = err.Error()
+		}
+		d.Success = err == nil
+		if err == nil || attempt > n.conf.MaxRetry {
+			now := time.Now()
+			d.FinishTime = &now
+		}
+		n.mu.Unlock()
+
+		if err == nil {
+			return
+		}
+		if attempt > n.conf.MaxRetry {
+			n.conf.Logger.Sugar().Warnf("webhook %s to %s failed after %d attempts: %v", d.ID, d.URL, attempt, err)
+			return
+		}
+		select {
+		case <-time.After(backoff):
+		case <-n.done:
+			return
+		}
+		backoff *= 2
+		if backoff > n.conf.MaxBackoff {
+			backoff = n.conf.MaxBackoff
+		}
+	}
+}
+
+func (n *Notifier) post(id string, cb *model.Callback, body []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, cb.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set(DeliveryHeader, id)
+	if cb.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(cb.Secret, body))
+	}
+
+	resp, err := n.conf.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+func (n *Notifier) record(d *Delivery) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if len(n.log) < n.conf.LogSize {
+		n.log = append(n.log, d)
+		n.next = len(n.log) % n.conf.LogSize
+		return
+	}
+	n.log[n.next] = d
+	n.next = (n.next + 1) % n.conf.LogSize
+}
+
+// Sign 计算请求体的签名，接收方使用相同的密钥计算并比较以验证请求来源
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func generateID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
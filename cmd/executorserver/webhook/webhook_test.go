@@ -0,0 +1,154 @@
+package webhook
+
+import (
+	"context"
+	"github.com/lxhcaicai/loj-judge/cmd/executorserver/model"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type attempt struct {
+	time      time.Time
+	id        string
+	signature string
+	body      []byte
+}
+
+// receiver 记录收到的投递，前 fail 次返回 500
+type receiver struct {
+	fail int
+
+	mu       sync.Mutex
+	attempts []attempt
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	r.attempts = append(r.attempts, attempt{
+		time:      time.Now(),
+		id:        req.Header.Get(DeliveryHeader),
+		signature: req.Header.Get(SignatureHeader),
+		body:      body,
+	})
+	n := len(r.attempts)
+	r.mu.Unlock()
+
+	if n <= r.fail {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (r *receiver) get() []attempt {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]attempt(nil), r.attempts...)
+}
+
+// waitFinish 等待唯一的投递完成并返回投递记录
+func waitFinish(t *testing.T, n *Notifier) Delivery {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if d := n.Deliveries(); len(d) == 1 && d[0].FinishTime != nil {
+			return d[0]
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("delivery not finished: %+v", n.Deliveries())
+	return Delivery{}
+}
+
+func TestNotifyRetry(t *testing.T) {
+	const (
+		secret  = "secret"
+		backoff = 50 * time.Millisecond
+	)
+	r := &receiver{fail: 2}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	n := New(Config{MaxRetry: 3, Backoff: backoff, LogSize: 4})
+	defer n.Shutdown(context.Background())
+	n.Notify(&model.Callback{URL: srv.URL, Secret: secret}, model.Response{RequestID: "req"})
+
+	d := waitFinish(t, n)
+	if !d.Success || d.Attempts != 3 || d.StatusCode != http.StatusNoContent || d.Error != "" {
+		t.Fatalf("unexpected delivery: %+v", d)
+	}
+	if d.RequestID != "req" || d.URL != srv.URL {
+		t.Fatalf("unexpected delivery: %+v", d)
+	}
+
+	attempts := r.get()
+	if len(attempts) != 3 {
+		t.Fatalf("expected 3 attempts, got %d", len(attempts))
+	}
+	for i, a := range attempts {
+		if a.id != d.ID {
+			t.Errorf("attempt %d: delivery id %q, expected %q", i, a.id, d.ID)
+		}
+		if a.signature != Sign(secret, a.body) {
+			t.Errorf("attempt %d: invalid signature %q", i, a.signature)
+		}
+	}
+	// 每次重试前的等待时间翻倍
+	if gap := attempts[1].time.Sub(attempts[0].time); gap < backoff {
+		t.Errorf("first retry after %v, expected at least %v", gap, backoff)
+	}
+	if gap := attempts[2].time.Sub(attempts[1].time); gap < 2*backoff {
+		t.Errorf("second retry after %v, expected at least %v", gap, 2*backoff)
+	}
+}
+
+func TestNotifyGiveUp(t *testing.T) {
+	r := &receiver{fail: 100}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	n := New(Config{MaxRetry: 2, Backoff: time.Millisecond, LogSize: 4})
+	defer n.Shutdown(context.Background())
+	n.Notify(&model.Callback{URL: srv.URL}, model.Response{RequestID: "req"})
+
+	d := waitFinish(t, n)
+	if d.Success || d.Attempts != 3 || d.StatusCode != http.StatusInternalServerError || d.Error == "" {
+		t.Fatalf("unexpected delivery: %+v", d)
+	}
+	if a := r.get(); len(a) != 3 || a[0].signature != "" {
+		t.Fatalf("unexpected attempts: %+v", a)
+	}
+}
+
+func TestDeliveryLog(t *testing.T) {
+	srv := httptest.NewServer(&receiver{})
+	defer srv.Close()
+
+	n := New(Config{LogSize: 2})
+	defer n.Shutdown(context.Background())
+
+	n.Notify(nil, model.Response{RequestID: "ignored"})
+	for _, id := range []string{"a", "b", "c"} {
+		n.Notify(&model.Callback{URL: srv.URL}, model.Response{RequestID: id})
+	}
+	if err := n.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// 只保留最近的 LogSize 条记录，从新到旧
+	d := n.Deliveries()
+	if len(d) != 2 || d[0].RequestID != "c" || d[1].RequestID != "b" {
+		t.Fatalf("unexpected delivery log: %+v", d)
+	}
+	for _, d := range d {
+		if !d.Success || d.Attempts != 1 {
+			t.Errorf("unexpected delivery: %+v", d)
+		}
+	}
+}
@@ -44,6 +44,7 @@ func (p *pool) Put(env envexec.Environment) {
 	}
 	// 如果容器在执行后死亡，不要将其放入池中
 	if err := e.Reset(); err != nil {
+		e.Destroy()
 		return
 	}
 
@@ -53,6 +54,14 @@ func (p *pool) Put(env envexec.Environment) {
 	p.env = append(p.env, e)
 }
 
+func (p *pool) Destroy(env envexec.Environment) {
+	e, ok := env.(Environment)
+	if !ok {
+		panic("invalid environment destroy")
+	}
+	e.Destroy()
+}
+
 func NewPool(builder EnvBuilder) worker.EnvironmentPool {
 	return &pool{
 		builder: builder,
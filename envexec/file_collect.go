@@ -103,6 +103,10 @@ func copyOutAndCollect(m Environment, c *Cmd, ptc []pipeCollector, newStoreFile
 				}
 			}()
 			<-p.done
+			// 仅等待输出完成，没有需要收集的内容 (FileWriter)
+			if p.buffer == nil {
+				return nil
+			}
 			if p.storage {
 				put(p.buffer, p.name)
 				if fi, err := p.buffer.Stat(); err == nil && fi.Size() > int64(p.limit) {
@@ -12,6 +12,7 @@ type pipeBuffer struct {
 	Limit  Size
 }
 
+// pipeCollector 定义需要在执行结束后收集的管道，buffer 为空时仅等待 done
 type pipeCollector struct {
 	done    <-chan struct{}
 	buffer  *os.File
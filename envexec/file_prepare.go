@@ -98,8 +98,12 @@ func prepareCmdFdTTY(c *Cmd, count int, newStoreFile NewStoreFile) (f []*os.File
 			}
 			hasOutput = true
 
+			done := make(chan struct{})
+			pipeToCollect = append(pipeToCollect, pipeCollector{done: done})
+
 			wg.Add(1)
 			go func() {
+				defer close(done)
 				defer wg.Done()
 				io.Copy(t.Writer, fPty)
 			}()
@@ -147,7 +151,10 @@ func prepareCmdFd(c *Cmd, count int, newFileStore NewStoreFile) (f []*os.File, p
 				if err != nil {
 					return nil, nil, fmt.Errorf("failed to create pipe %v", err)
 				}
-				go w.ReadFrom(t.Reader)
+				go func() {
+					defer w.Close()
+					w.ReadFrom(t.Reader)
+				}()
 
 				files[j] = r
 			} else {
@@ -198,11 +205,13 @@ func prepareCmdFd(c *Cmd, count int, newFileStore NewStoreFile) (f []*os.File, p
 			}
 
 		case *FileWriter:
-			_, w, err := newPipe(t.Writer, t.Limit)
+			done, w, err := newPipe(t.Writer, t.Limit)
 			if err != nil {
 				return nil, nil, fmt.Errorf("failed to create pipe %v", err)
 			}
 			files[j] = w
+			// 等待写入完成后再返回结果，保证输出先于结果送达
+			pipeToCollect = append(pipeToCollect, pipeCollector{done: done})
 
 		default:
 			return nil, nil, fmt.Errorf("unknown file type %v %t", t, t)
@@ -334,6 +343,8 @@ func pipeProxy(p Pipe, out1 *os.File, in2 *os.File, buffer *os.File) *pipeCollec
 		r := io.TeeReader(io.LimitReader(out1, int64(limit)), buffer)
 		io.Copy(in2, r)
 		close(done)
+		// 超过限制后继续转发但不再记录，结束后关闭以通知对端
+		copyAndClose()
 	}()
 
 	return &pipeCollector{
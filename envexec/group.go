@@ -3,6 +3,8 @@ package envexec
 import (
 	"context"
 	"golang.org/x/sync/errgroup"
+	"sync"
+	"time"
 )
 
 // Pipe 定义了并行Cmd之间的管道
@@ -39,6 +41,10 @@ type Group struct {
 
 	// NewStoreFile 定义用于创建存储文件的接口
 	NewStoreFile NewStoreFile
+
+	// ExitKill 定义第 i 个命令退出后等待多久终止其他命令，
+	// 小于 0 或者未设置表示不终止
+	ExitKill []time.Duration
 }
 
 // Run 启动CMD并返回执行结果
@@ -49,14 +55,48 @@ func (r *Group) Run(ctx context.Context) ([]Result, error) {
 		return nil, err
 	}
 
+	// 每个命令使用独立的 context，以便在其他命令退出后终止
+	ctxs := make([]context.Context, len(r.Cmd))
+	cancels := make([]context.CancelFunc, len(r.Cmd))
+	for i := range r.Cmd {
+		ctxs[i], cancels[i] = context.WithCancel(ctx)
+	}
+	killOthers := func(i int) {
+		for j, cancel := range cancels {
+			if j != i {
+				cancel()
+			}
+		}
+	}
+	var timers []*time.Timer
+	var tl sync.Mutex
+	defer func() {
+		tl.Lock()
+		defer tl.Unlock()
+		for _, t := range timers {
+			t.Stop()
+		}
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}()
+
 	// 等待所有CMD命令完成
 	var g errgroup.Group
 	result := make([]Result, len(r.Cmd))
 	for i, c := range r.Cmd {
 		i, c := i, c
+		d := r.exitKill(i)
 		g.Go(func() error {
-			r, err := runSingle(ctx, c, fds[i], pipeToCollect[i], r.NewStoreFile)
+			r, err := runSingle(ctxs[i], c, fds[i], pipeToCollect[i], r.NewStoreFile)
 			result[i] = r
+			if d == 0 {
+				killOthers(i)
+			} else if d > 0 {
+				tl.Lock()
+				timers = append(timers, time.AfterFunc(d, func() { killOthers(i) }))
+				tl.Unlock()
+			}
 			if err != nil {
 				result[i].Status = StatusInternalError
 				result[i].Error = err.Error()
@@ -68,3 +108,10 @@ func (r *Group) Run(ctx context.Context) ([]Result, error) {
 	err = g.Wait()
 	return result, nil
 }
+
+func (r *Group) exitKill(i int) time.Duration {
+	if i < len(r.ExitKill) {
+		return r.ExitKill[i]
+	}
+	return -1
+}
@@ -10,6 +10,7 @@ var statusToString = []string{
 	"Accepted",
 	"Wrong Answer",
 	"Partially Correct",
+	"Presentation Error",
 	"Memory Limit Exceeded",
 	"Time Limit Exceeded",
 	"Output Limit Exceeded",
@@ -35,6 +36,7 @@ const (
 	StatusAccepted
 	StatusWrongAnswer
 	StatusPartiallyCorrect
+	StatusPresentationError
 
 	// 错误退出
 	StatusMemoryLimitExceeded
@@ -71,6 +71,10 @@ func (s *fileLocalStore) List() map[string]string {
 
 	names := make(map[string]string, len(fi))
 	for _, f := range fi {
+		// 目录下的子目录 (例如任务日志) 不是存储的文件
+		if f.IsDir() {
+			continue
+		}
 		names[f.Name()] = s.name[f.Name()]
 	}
 	return names
@@ -0,0 +1,334 @@
+package worker
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"github.com/lxhcaicai/loj-judge/envexec"
+	"github.com/lxhcaicai/loj-judge/filestore"
+	"hash"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 缓存结果中收集的文件 (例如编译信息) 的最大总长度，超过时不缓存
+const resultCacheFileMax = 64 << 10
+
+// resultCache 以命令输入内容的哈希为键缓存成功的执行结果 (通常为编译)，
+// 按照最近使用淘汰，超过 ttl 的条目失效。
+// 缓存持有 copyOutCached 文件的副本，命中时复制为新的文件交给请求方，
+// 因此请求方删除自己的文件不会影响缓存以及其他请求
+type resultCache struct {
+	size int
+	ttl  time.Duration
+	fs   filestore.FileStore
+
+	mu      sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+type resultCacheEntry struct {
+	key     string
+	result  Result // FileIDs 为缓存持有的副本
+	files   map[string][]byte
+	created time.Time
+}
+
+func newResultCache(size int, ttl time.Duration, fs filestore.FileStore) *resultCache {
+	if size <= 0 {
+		return nil
+	}
+	return &resultCache{
+		size:    size,
+		ttl:     ttl,
+		fs:      fs,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// get 返回缓存的结果，文件被复制为新的文件 id。
+// 缓存持有的文件已经不在文件存储中 (例如过期) 时视为未命中
+func (c *resultCache) get(key string) (Result, bool) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if !ok {
+		c.mu.Unlock()
+		return Result{}, false
+	}
+	ce := e.Value.(*resultCacheEntry)
+	if c.ttl > 0 && time.Since(ce.created) > c.ttl {
+		c.remove(e)
+		c.mu.Unlock()
+		return Result{}, false
+	}
+	for _, id := range ce.result.FileIDs {
+		if _, f := c.fs.Get(id); f == nil {
+			c.remove(e)
+			c.mu.Unlock()
+			return Result{}, false
+		}
+	}
+	c.lru.MoveToFront(e)
+	c.mu.Unlock()
+
+	// 条目中的字段在放入缓存后不再修改，可以在锁外复制文件
+	res := ce.result
+	fileIDs, err := copyFiles(c.fs, ce.result.FileIDs)
+	if err != nil {
+		return Result{}, false
+	}
+	res.FileIDs = fileIDs
+	res.Files = make(map[string]*os.File, len(ce.files))
+	for name, b := range ce.files {
+		f, err := c.fs.New()
+		if err == nil {
+			_, err = f.Write(b)
+		}
+		if err != nil {
+			if f != nil {
+				f.Close()
+				os.Remove(f.Name())
+			}
+			for _, f := range res.Files {
+				f.Close()
+				os.Remove(f.Name())
+			}
+			for _, id := range fileIDs {
+				c.fs.Remove(id)
+			}
+			return Result{}, false
+		}
+		res.Files[name] = f
+	}
+	res.Cached = true
+	return res, true
+}
+
+// put 缓存结果，收集的文件内容以及 copyOutCached 文件被复制保存
+func (c *resultCache) put(key string, res Result) {
+	files := make(map[string][]byte, len(res.Files))
+	total := int64(0)
+	for name, f := range res.Files {
+		fi, err := f.Stat()
+		if err != nil {
+			return
+		}
+		total += fi.Size()
+		if total > resultCacheFileMax {
+			return
+		}
+		b, err := io.ReadAll(io.NewSectionReader(f, 0, fi.Size()))
+		if err != nil {
+			return
+		}
+		files[name] = b
+	}
+	res.Files = nil
+	fileIDs, err := copyFiles(c.fs, res.FileIDs)
+	if err != nil {
+		return
+	}
+	res.FileIDs = fileIDs
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		c.remove(e)
+	}
+	c.entries[key] = c.lru.PushFront(&resultCacheEntry{
+		key:     key,
+		result:  res,
+		files:   files,
+		created: time.Now(),
+	})
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+	}
+}
+
+// remove 删除条目以及缓存持有的文件，调用时需要持有锁
+func (c *resultCache) remove(e *list.Element) {
+	ce := e.Value.(*resultCacheEntry)
+	c.lru.Remove(e)
+	delete(c.entries, ce.key)
+	for _, id := range ce.result.FileIDs {
+		c.fs.Remove(id)
+	}
+}
+
+// copyFiles 将文件存储中的文件复制为新的文件，失败时删除已经复制的文件
+func copyFiles(fs filestore.FileStore, ids map[string]string) (map[string]string, error) {
+	rt := make(map[string]string, len(ids))
+	for name, id := range ids {
+		newID, err := copyFile(fs, id)
+		if err != nil {
+			for _, id := range rt {
+				fs.Remove(id)
+			}
+			return nil, err
+		}
+		rt[name] = newID
+	}
+	return rt, nil
+}
+
+func copyFile(fs filestore.FileStore, id string) (string, error) {
+	name, f := fs.Get(id)
+	if f == nil {
+		return "", fmt.Errorf("copy file: %s not exists", id)
+	}
+	r, err := envexec.FileToReader(f)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	nf, err := fs.New()
+	if err != nil {
+		return "", err
+	}
+	defer nf.Close()
+	if _, err := io.Copy(nf, r); err != nil {
+		os.Remove(nf.Name())
+		return "", err
+	}
+	newID, err := fs.Add(name, nf.Name())
+	if err != nil {
+		os.Remove(nf.Name())
+		return "", err
+	}
+	return newID, nil
+}
+
+// cacheKey 计算命令的哈希，包括参数、环境变量、限制以及所有输入文件的内容。
+// 命令包含无法重复读取的输入或者比较 / 评测时返回 false
+func (w *worker) cacheKey(c Cmd) (string, bool) {
+	if c.Compare != nil || c.Checker != nil || c.TTY {
+		return "", false
+	}
+	h := sha256.New()
+	writeStrings(h, c.Args)
+	writeStrings(h, c.Env)
+	writeUint(h,
+		uint64(c.CPULimit), uint64(c.ClockLimit), uint64(c.MemoryLimit), uint64(c.StackLimit),
+		uint64(c.OutputLimit), c.ProcLimit, c.OpenFileLimit, c.CPURateLimit, c.CopyOutMax)
+	writeString(h, c.CPUSetLimit)
+
+	writeUint(h, uint64(len(c.Files)))
+	for _, f := range c.Files {
+		if !w.writeCmdFile(h, f) {
+			return "", false
+		}
+	}
+
+	names := make([]string, 0, len(c.CopyIn))
+	for n := range c.CopyIn {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	writeUint(h, uint64(len(names)))
+	for _, n := range names {
+		writeString(h, n)
+		if !w.writeCmdFile(h, c.CopyIn[n]) {
+			return "", false
+		}
+	}
+
+	links := make([]string, 0, len(c.Symlinks))
+	for k, v := range c.Symlinks {
+		links = append(links, k+"\x00"+v)
+	}
+	sort.Strings(links)
+	writeStrings(h, links)
+
+	for _, co := range [][]CmdCopyOutFile{c.CopyOut, c.CopyOutCached} {
+		writeUint(h, uint64(len(co)))
+		for _, f := range co {
+			writeString(h, f.Name)
+			writeBool(h, f.Optional)
+		}
+	}
+	writeString(h, c.CopyOutDir)
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+func (w *worker) writeCmdFile(h hash.Hash, f CmdFile) bool {
+	switch f := f.(type) {
+	case nil:
+		writeString(h, "nil")
+	case *LocalFile:
+		writeString(h, "content")
+		return writeFileContent(h, envexec.NewFileInput(f.Src))
+	case *MemoryFile:
+		writeString(h, "content")
+		writeUint(h, uint64(len(f.Content)))
+		h.Write(f.Content)
+	case *CachedFile:
+		_, ef := w.fs.Get(f.FileID)
+		if ef == nil {
+			return false
+		}
+		writeString(h, "content")
+		return writeFileContent(h, ef)
+	case *Collector:
+		writeString(h, "collector")
+		writeString(h, f.Name)
+		writeUint(h, uint64(f.Max))
+		writeBool(h, f.Pipe)
+	default:
+		return false
+	}
+	return true
+}
+
+func writeFileContent(h hash.Hash, f envexec.File) bool {
+	r, err := envexec.FileToReader(f)
+	if err != nil {
+		return false
+	}
+	defer r.Close()
+
+	// 内容长度未知，先写入内容的哈希以避免与之后的字段混淆
+	fh := sha256.New()
+	if _, err := io.Copy(fh, r); err != nil {
+		return false
+	}
+	h.Write(fh.Sum(nil))
+	return true
+}
+
+func writeString(h hash.Hash, s string) {
+	writeUint(h, uint64(len(s)))
+	io.WriteString(h, s)
+}
+
+func writeStrings(h hash.Hash, s []string) {
+	writeUint(h, uint64(len(s)))
+	for _, v := range s {
+		writeString(h, v)
+	}
+}
+
+func writeUint(h hash.Hash, v ...uint64) {
+	var b [8]byte
+	for _, n := range v {
+		binary.LittleEndian.PutUint64(b[:], n)
+		h.Write(b[:])
+	}
+}
+
+func writeBool(h hash.Hash, v bool) {
+	if v {
+		writeUint(h, 1)
+	} else {
+		writeUint(h, 0)
+	}
+}
@@ -0,0 +1,89 @@
+package worker
+
+import (
+	"github.com/lxhcaicai/loj-judge/envexec"
+	"github.com/lxhcaicai/loj-judge/filestore"
+	"io"
+	"testing"
+)
+
+func addFile(t *testing.T, fs filestore.FileStore, content string) string {
+	t.Helper()
+	f, err := fs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	id, err := fs.Add("a.out", f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func readFile(t *testing.T, fs filestore.FileStore, id string) string {
+	t.Helper()
+	_, f := fs.Get(id)
+	if f == nil {
+		t.Fatalf("file %s not exists", id)
+	}
+	r, err := envexec.FileToReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestResultCacheOwnsFiles(t *testing.T) {
+	fs := filestore.NewFileLocalStore(t.TempDir())
+	c := newResultCache(1, 0, fs)
+
+	id := addFile(t, fs, "binary")
+	c.put("key", Result{Status: envexec.StatusAccepted, FileIDs: map[string]string{"a.out": id}})
+	// 请求方删除自己的文件不影响缓存
+	fs.Remove(id)
+
+	r1, ok := c.get("key")
+	if !ok {
+		t.Fatal("expected cache hit")
+	}
+	r2, ok := c.get("key")
+	if !ok {
+		t.Fatal("expected cache hit")
+	}
+	id1, id2 := r1.FileIDs["a.out"], r2.FileIDs["a.out"]
+	if !r1.Cached || id1 == "" || id1 == id || id1 == id2 {
+		t.Fatalf("expected fresh file ids, got %q %q (original %q)", id1, id2, id)
+	}
+
+	// 一个请求删除命中得到的文件不影响其他请求以及之后的命中
+	fs.Remove(id1)
+	if got := readFile(t, fs, id2); got != "binary" {
+		t.Fatalf("unexpected content %q", got)
+	}
+	r3, ok := c.get("key")
+	if !ok {
+		t.Fatal("expected cache hit after deleting a hit's file")
+	}
+	if got := readFile(t, fs, r3.FileIDs["a.out"]); got != "binary" {
+		t.Fatalf("unexpected content %q", got)
+	}
+
+	// 淘汰条目时删除缓存持有的文件
+	before := len(fs.List())
+	c.put("other", Result{Status: envexec.StatusAccepted})
+	if after := len(fs.List()); after != before-1 {
+		t.Fatalf("expected evicted entry's file removed, %d files before, %d after", before, after)
+	}
+	if _, ok := c.get("key"); ok {
+		t.Fatal("expected evicted entry missed")
+	}
+}
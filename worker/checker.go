@@ -0,0 +1,185 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/lxhcaicai/loj-judge/envexec"
+	"os"
+	"strconv"
+)
+
+// testlib 评测程序的退出码
+const (
+	checkerExitAccepted      = 0
+	checkerExitWrongAnswer   = 1
+	checkerExitPresentation  = 2
+	checkerExitFail          = 3
+	checkerExitDirt          = 4
+	checkerExitPoints        = 7
+	checkerExitUnexpectedEOF = 8
+	checkerExitPartially     = 16 // _pc(x) 的退出码为 16 + x，x 为得分的百分比
+)
+
+// 评测程序工作目录中的文件名
+const (
+	checkerInputName  = "input"
+	checkerOutputName = "output"
+	checkerAnswerName = "answer"
+	checkerStdoutName = "stdout"
+	checkerStderrName = "stderr"
+)
+
+const (
+	checkerMessageMax = 4 << 10
+	checkerPoints     = "points "
+)
+
+// Checker 定义在执行成功后在独立环境中运行的评测程序，
+// 按照 testlib 的方式调用: <args> input output answer
+type Checker struct {
+	Cmd        Cmd     // 评测程序命令，标准输入输出由 worker 准备
+	Name       string  // 作为选手输出的文件名 (收集的文件或者 copyOut)
+	Input      CmdFile // 输入文件，为空时使用空文件
+	Answer     CmdFile // 答案文件
+	KeepOutput bool    // 评测后仍然返回输出文件
+}
+
+// checkResult 运行评测程序并根据退出码更新结果状态
+func (w *worker) checkResult(ctx context.Context, res *Result, result envexec.Result, c *Checker) {
+	output, ok := result.Files[c.Name]
+	if !ok {
+		res.Status = envexec.StatusWrongAnswer
+		res.Message = fmt.Sprintf("output file %s not found", c.Name)
+		return
+	}
+	defer func() {
+		if !c.KeepOutput {
+			delete(result.Files, c.Name)
+			output.Close()
+			os.Remove(output.Name())
+		}
+	}()
+
+	status, score, msg, err := w.runChecker(ctx, output.Name(), c)
+	if err != nil {
+		res.Status = envexec.StatusJudgementFailed
+		res.Message = fmt.Sprintf("checker: %v", err)
+		return
+	}
+	res.Status = status
+	res.Score = score
+	res.Message = msg
+}
+
+func (w *worker) runChecker(ctx context.Context, output string, c *Checker) (envexec.Status, float64, string, error) {
+	if c.Answer == nil {
+		return envexec.StatusInvalid, 0, "", fmt.Errorf("answer file not provided")
+	}
+	input := c.Input
+	if input == nil {
+		input = &MemoryFile{}
+	}
+
+	rc := c.Cmd
+	rc.Args = append(append([]string{}, rc.Args...), checkerInputName, checkerOutputName, checkerAnswerName)
+	rc.Files = []CmdFile{
+		&MemoryFile{},
+		&Collector{Name: checkerStdoutName, Max: checkerMessageMax},
+		&Collector{Name: checkerStderrName, Max: checkerMessageMax},
+	}
+	rc.CopyIn = make(map[string]CmdFile, len(c.Cmd.CopyIn)+3)
+	for k, v := range c.Cmd.CopyIn {
+		rc.CopyIn[k] = v
+	}
+	rc.CopyIn[checkerInputName] = input
+	rc.CopyIn[checkerOutputName] = &LocalFile{Src: output}
+	rc.CopyIn[checkerAnswerName] = c.Answer
+	rc.CopyOut = nil
+	rc.CopyOutCached = nil
+	rc.Compare = nil
+	rc.Checker = nil
+
+	cmd, err := w.prepareCmd(rc, make(map[string]bool))
+	if err != nil {
+		return envexec.StatusInvalid, 0, "", err
+	}
+	env, err := w.envPool.Get()
+	if err != nil {
+		return envexec.StatusInvalid, 0, "", fmt.Errorf("failed to get environment %v", err)
+	}
+	cmd.Environment = env
+
+	s := &envexec.Single{
+		Cmd:          cmd,
+		NewStoreFile: w.fs.New,
+	}
+	result, err := s.Run(ctx)
+	w.releaseEnv(env, envFailed(result.Status, result.Error))
+	msg := readCheckerMessage(result.Files)
+	if err != nil {
+		return envexec.StatusInvalid, 0, "", err
+	}
+
+	switch result.Status {
+	case envexec.StatusAccepted, envexec.StatusNonzeroExitStatus:
+	default:
+		if result.Error != "" {
+			return envexec.StatusInvalid, 0, "", fmt.Errorf("%v: %s", result.Status, result.Error)
+		}
+		return envexec.StatusInvalid, 0, "", fmt.Errorf("%v", result.Status)
+	}
+
+	return testlibResult(result.ExitStatus, msg)
+}
+
+// testlibResult 将 testlib 评测程序或交互器的退出码转换为结果状态
+func testlibResult(exitStatus int, msg string) (envexec.Status, float64, string, error) {
+	switch exitStatus {
+	case checkerExitAccepted:
+		return envexec.StatusAccepted, 0, msg, nil
+	case checkerExitWrongAnswer, checkerExitDirt, checkerExitUnexpectedEOF:
+		return envexec.StatusWrongAnswer, 0, msg, nil
+	case checkerExitPresentation:
+		return envexec.StatusPresentationError, 0, msg, nil
+	case checkerExitPoints:
+		score, ok := parseCheckerPoints(msg)
+		if !ok {
+			return envexec.StatusInvalid, 0, "", fmt.Errorf("invalid points message %s", shorten([]byte(msg)))
+		}
+		return envexec.StatusPartiallyCorrect, score, msg, nil
+	case checkerExitFail:
+		return envexec.StatusJudgementFailed, 0, msg, nil
+	default:
+		if exitStatus >= checkerExitPartially {
+			return envexec.StatusPartiallyCorrect, float64(exitStatus-checkerExitPartially) / 100, msg, nil
+		}
+		return envexec.StatusJudgementFailed, 0, fmt.Sprintf("unexpected exit status %d: %s", exitStatus, msg), nil
+	}
+}
+
+// readCheckerMessage 读取评测程序写入标准错误的信息并删除临时文件
+func readCheckerMessage(files map[string]*os.File) string {
+	var msg string
+	for name, f := range files {
+		if name == checkerStderrName {
+			msg = readMessage(f)
+		}
+		f.Close()
+		os.Remove(f.Name())
+	}
+	return msg
+}
+
+// parseCheckerPoints 解析 testlib quitp 输出的 "points <score> <message>"
+func parseCheckerPoints(msg string) (float64, bool) {
+	b := bytes.TrimPrefix([]byte(msg), []byte(checkerPoints))
+	if i := bytes.IndexAny(b, " \t\r\n"); i >= 0 {
+		b = b[:i]
+	}
+	score, err := strconv.ParseFloat(string(b), 64)
+	if err != nil {
+		return 0, false
+	}
+	return score, true
+}
@@ -0,0 +1,35 @@
+package worker
+
+import (
+	"github.com/lxhcaicai/loj-judge/envexec"
+	"testing"
+)
+
+func TestTestlibResult(t *testing.T) {
+	for _, tc := range []struct {
+		exitStatus int
+		msg        string
+		status     envexec.Status
+		score      float64
+	}{
+		{checkerExitAccepted, "ok", envexec.StatusAccepted, 0},
+		{checkerExitWrongAnswer, "wrong", envexec.StatusWrongAnswer, 0},
+		{checkerExitUnexpectedEOF, "eof", envexec.StatusWrongAnswer, 0},
+		{checkerExitPresentation, "pe", envexec.StatusPresentationError, 0},
+		{checkerExitPoints, "points 0.25 partial", envexec.StatusPartiallyCorrect, 0.25},
+		{checkerExitFail, "fail", envexec.StatusJudgementFailed, 0},
+		{5, "", envexec.StatusJudgementFailed, 0},
+		// _pc(x) 的退出码为 16 + x
+		{checkerExitPartially, "pc 0", envexec.StatusPartiallyCorrect, 0},
+		{checkerExitPartially + 50, "pc 50", envexec.StatusPartiallyCorrect, 0.5},
+		{checkerExitPartially + 100, "pc 100", envexec.StatusPartiallyCorrect, 1},
+	} {
+		status, score, _, err := testlibResult(tc.exitStatus, tc.msg)
+		if err != nil || status != tc.status || score != tc.score {
+			t.Errorf("exit %d: expected %v %v, got %v %v %v", tc.exitStatus, tc.status, tc.score, status, score, err)
+		}
+	}
+	if _, _, _, err := testlibResult(checkerExitPoints, "points x"); err == nil {
+		t.Error("expected invalid points message rejected")
+	}
+}
@@ -0,0 +1,292 @@
+package worker
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/lxhcaicai/loj-judge/envexec"
+	"io"
+	"math"
+	"os"
+	"strconv"
+)
+
+// CompareMode 定义输出与答案的比较方式
+type CompareMode int
+
+const (
+	// CompareIgnoreTrailingSpace 忽略行末空白以及文末空行，逐行比较
+	CompareIgnoreTrailingSpace CompareMode = iota
+	// CompareExact 逐字节比较
+	CompareExact
+	// CompareToken 忽略所有空白，逐个单词比较
+	CompareToken
+	// CompareFloat 逐个单词比较，数字在误差范围内视为相同
+	CompareFloat
+)
+
+const (
+	defaultCompareEpsilon = 1e-6
+	compareMessageMax     = 32
+	compareBufferSize     = 64 << 10
+)
+
+// Compare 定义命令执行完成后将输出文件与答案比较
+type Compare struct {
+	Name       string  // 要比较的输出文件名 (收集的文件或者 copyOut)
+	Answer     CmdFile // 答案文件
+	Mode       CompareMode
+	Epsilon    float64 // CompareFloat 模式下允许的绝对或相对误差
+	KeepOutput bool    // 比较后仍然返回输出文件
+}
+
+// compareResult 比较输出并更新结果状态
+func (w *worker) compareResult(res *Result, result envexec.Result, c *Compare) {
+	output, ok := result.Files[c.Name]
+	if !ok {
+		res.Status = envexec.StatusWrongAnswer
+		res.Message = fmt.Sprintf("output file %s not found", c.Name)
+		return
+	}
+	if c.Answer == nil {
+		res.Status = envexec.StatusJudgementFailed
+		res.Message = "answer file not provided"
+		return
+	}
+	openOutput := func() (io.ReadCloser, error) {
+		fi, err := output.Stat()
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(io.NewSectionReader(output, 0, fi.Size())), nil
+	}
+	openAnswer := func() (io.ReadCloser, error) {
+		f, err := c.Answer.EnvFile(w.fs)
+		if err != nil {
+			return nil, err
+		}
+		return envexec.FileToReader(f)
+	}
+
+	status, msg, err := compareFiles(openOutput, openAnswer, c.Mode, c.Epsilon)
+	if err != nil {
+		res.Status = envexec.StatusJudgementFailed
+		res.Message = fmt.Sprintf("compare: %v", err)
+		return
+	}
+	res.Status = status
+	res.Message = msg
+
+	if !c.KeepOutput {
+		delete(result.Files, c.Name)
+		output.Close()
+		os.Remove(output.Name())
+	}
+}
+
+type openFunc func() (io.ReadCloser, error)
+
+// compareFiles 按照模式比较两个文件，返回状态以及差异说明
+func compareFiles(output, answer openFunc, mode CompareMode, epsilon float64) (envexec.Status, string, error) {
+	var ok bool
+	var msg string
+	var err error
+	switch mode {
+	case CompareExact:
+		ok, msg, err = compareWith(output, answer, compareLines(false))
+		if err != nil || ok {
+			break
+		}
+		// 仅空白不同时为格式错误
+		if pe, _, err := compareWith(output, answer, compareTokens(0, false)); err == nil && pe {
+			return envexec.StatusPresentationError, msg, nil
+		}
+	case CompareIgnoreTrailingSpace:
+		ok, msg, err = compareWith(output, answer, compareLines(true))
+		if err != nil || ok {
+			break
+		}
+		if pe, _, err := compareWith(output, answer, compareTokens(0, false)); err == nil && pe {
+			return envexec.StatusPresentationError, msg, nil
+		}
+	case CompareToken:
+		ok, msg, err = compareWith(output, answer, compareTokens(0, false))
+	case CompareFloat:
+		if epsilon <= 0 {
+			epsilon = defaultCompareEpsilon
+		}
+		ok, msg, err = compareWith(output, answer, compareTokens(epsilon, true))
+	default:
+		return envexec.StatusInvalid, "", fmt.Errorf("unknown compare mode %d", mode)
+	}
+	if err != nil {
+		return envexec.StatusInvalid, "", err
+	}
+	if ok {
+		return envexec.StatusAccepted, "", nil
+	}
+	return envexec.StatusWrongAnswer, msg, nil
+}
+
+type compareFunc func(output, answer io.Reader) (bool, string, error)
+
+func compareWith(output, answer openFunc, fn compareFunc) (bool, string, error) {
+	o, err := output()
+	if err != nil {
+		return false, "", fmt.Errorf("open output: %v", err)
+	}
+	defer o.Close()
+
+	a, err := answer()
+	if err != nil {
+		return false, "", fmt.Errorf("open answer: %v", err)
+	}
+	defer a.Close()
+
+	return fn(o, a)
+}
+
+func compareLines(trimSpace bool) compareFunc {
+	return func(output, answer io.Reader) (bool, string, error) {
+		o := bufio.NewReaderSize(output, compareBufferSize)
+		a := bufio.NewReaderSize(answer, compareBufferSize)
+		for line := 1; ; line++ {
+			ol, oEOF, err := readLine(o, trimSpace)
+			if err != nil {
+				return false, "", err
+			}
+			al, aEOF, err := readLine(a, trimSpace)
+			if err != nil {
+				return false, "", err
+			}
+			if oEOF && aEOF {
+				return true, "", nil
+			}
+			// 文末多余的空行不影响结果
+			if trimSpace && oEOF && len(al) == 0 {
+				ok, err := onlySpaceLeft(a)
+				if err != nil || ok {
+					return ok, "", err
+				}
+			}
+			if trimSpace && aEOF && len(ol) == 0 {
+				ok, err := onlySpaceLeft(o)
+				if err != nil || ok {
+					return ok, "", err
+				}
+			}
+			if oEOF {
+				return false, fmt.Sprintf("line %d: expected %s, got EOF", line, shorten(al)), nil
+			}
+			if aEOF {
+				return false, fmt.Sprintf("line %d: expected EOF, got %s", line, shorten(ol)), nil
+			}
+			if !bytes.Equal(ol, al) {
+				return false, fmt.Sprintf("line %d: expected %s, got %s", line, shorten(al), shorten(ol)), nil
+			}
+		}
+	}
+}
+
+// readLine 读取一行 (包括换行符)，没有更多内容时返回 eof
+func readLine(r *bufio.Reader, trimSpace bool) ([]byte, bool, error) {
+	l, err := r.ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return nil, false, err
+	}
+	if err == io.EOF && len(l) == 0 {
+		return nil, true, nil
+	}
+	if trimSpace {
+		l = bytes.TrimRight(l, " \t\r\n\v\f")
+	}
+	return l, false, nil
+}
+
+// onlySpaceLeft 检查剩余内容是否只有空白
+func onlySpaceLeft(r *bufio.Reader) (bool, error) {
+	for {
+		b, err := r.ReadByte()
+		if err == io.EOF {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if !isSpace(b) {
+			return false, nil
+		}
+	}
+}
+
+func compareTokens(epsilon float64, float bool) compareFunc {
+	return func(output, answer io.Reader) (bool, string, error) {
+		o := newTokenScanner(output)
+		a := newTokenScanner(answer)
+		for token := 1; ; token++ {
+			oOk := o.Scan()
+			aOk := a.Scan()
+			if err := o.Err(); err != nil {
+				return false, "", err
+			}
+			if err := a.Err(); err != nil {
+				return false, "", err
+			}
+			switch {
+			case !oOk && !aOk:
+				return true, "", nil
+			case !oOk:
+				return false, fmt.Sprintf("token %d: expected %s, got EOF", token, shorten(a.Bytes())), nil
+			case !aOk:
+				return false, fmt.Sprintf("token %d: expected EOF, got %s", token, shorten(o.Bytes())), nil
+			}
+			if bytes.Equal(o.Bytes(), a.Bytes()) {
+				continue
+			}
+			if float && floatEqual(o.Bytes(), a.Bytes(), epsilon) {
+				continue
+			}
+			return false, fmt.Sprintf("token %d: expected %s, got %s", token, shorten(a.Bytes()), shorten(o.Bytes())), nil
+		}
+	}
+}
+
+func newTokenScanner(r io.Reader) *bufio.Scanner {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, compareBufferSize), math.MaxInt32)
+	s.Split(bufio.ScanWords)
+	return s
+}
+
+func floatEqual(o, a []byte, epsilon float64) bool {
+	of, err := strconv.ParseFloat(string(o), 64)
+	if err != nil {
+		return false
+	}
+	af, err := strconv.ParseFloat(string(a), 64)
+	if err != nil {
+		return false
+	}
+	if math.IsNaN(of) || math.IsNaN(af) {
+		return math.IsNaN(of) && math.IsNaN(af)
+	}
+	diff := math.Abs(of - af)
+	return diff <= epsilon || diff <= epsilon*math.Abs(af)
+}
+
+func isSpace(b byte) bool {
+	switch b {
+	case ' ', '\t', '\r', '\n', '\v', '\f':
+		return true
+	}
+	return false
+}
+
+// shorten 截断差异说明中过长的内容
+func shorten(b []byte) string {
+	b = bytes.TrimRight(b, "\r\n")
+	if len(b) > compareMessageMax {
+		return strconv.Quote(string(b[:compareMessageMax])) + "..."
+	}
+	return strconv.Quote(string(b))
+}
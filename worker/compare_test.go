@@ -0,0 +1,67 @@
+package worker
+
+import (
+	"github.com/lxhcaicai/loj-judge/envexec"
+	"io"
+	"strings"
+	"testing"
+)
+
+func openString(s string) openFunc {
+	return func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader(s)), nil
+	}
+}
+
+func TestCompareFiles(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		mode     CompareMode
+		epsilon  float64
+		output   string
+		answer   string
+		expected envexec.Status
+	}{
+		{"exact equal", CompareExact, 0, "1 2\n3\n", "1 2\n3\n", envexec.StatusAccepted},
+		{"exact trailing space", CompareExact, 0, "1 2 \n3\n", "1 2\n3\n", envexec.StatusPresentationError},
+		{"exact missing newline", CompareExact, 0, "1 2\n3", "1 2\n3\n", envexec.StatusPresentationError},
+		{"exact wrong", CompareExact, 0, "1 2\n4\n", "1 2\n3\n", envexec.StatusWrongAnswer},
+
+		{"trailing space ignored", CompareIgnoreTrailingSpace, 0, "1 2 \r\n3\t\n", "1 2\n3\n", envexec.StatusAccepted},
+		{"trailing blank lines ignored", CompareIgnoreTrailingSpace, 0, "1 2\n3\n\n\n", "1 2\n3", envexec.StatusAccepted},
+		{"trailing blank lines in answer ignored", CompareIgnoreTrailingSpace, 0, "1 2\n3", "1 2\n3\n\n", envexec.StatusAccepted},
+		{"different line break", CompareIgnoreTrailingSpace, 0, "1\n2\n3\n", "1 2\n3\n", envexec.StatusPresentationError},
+		{"leading space", CompareIgnoreTrailingSpace, 0, " 1 2\n3\n", "1 2\n3\n", envexec.StatusPresentationError},
+		{"extra line", CompareIgnoreTrailingSpace, 0, "1 2\n3\n4\n", "1 2\n3\n", envexec.StatusWrongAnswer},
+		{"missing line", CompareIgnoreTrailingSpace, 0, "1 2\n", "1 2\n3\n", envexec.StatusWrongAnswer},
+
+		{"token whitespace ignored", CompareToken, 0, "1\n\n2   3", "1 2 3\n", envexec.StatusAccepted},
+		{"token wrong", CompareToken, 0, "1 2 4", "1 2 3", envexec.StatusWrongAnswer},
+		{"token missing", CompareToken, 0, "1 2", "1 2 3", envexec.StatusWrongAnswer},
+		{"token no float tolerance", CompareToken, 0, "1.0", "1", envexec.StatusWrongAnswer},
+
+		{"float absolute error", CompareFloat, 1e-3, "1.0005 abc", "1 abc", envexec.StatusAccepted},
+		{"float relative error", CompareFloat, 1e-3, "1000.5", "1000", envexec.StatusAccepted},
+		{"float out of range", CompareFloat, 1e-3, "1.01", "1", envexec.StatusWrongAnswer},
+		{"float default epsilon", CompareFloat, 0, "0.3333333", "0.33333333", envexec.StatusAccepted},
+		{"float default epsilon exceeded", CompareFloat, 0, "0.333", "0.33333333", envexec.StatusWrongAnswer},
+		{"float nan", CompareFloat, 0, "nan", "NaN", envexec.StatusAccepted},
+		{"float nan with number", CompareFloat, 0, "nan", "1", envexec.StatusWrongAnswer},
+		{"float word mismatch", CompareFloat, 0, "abc", "abd", envexec.StatusWrongAnswer},
+	} {
+		status, msg, err := compareFiles(openString(tc.output), openString(tc.answer), tc.mode, tc.epsilon)
+		if err != nil || status != tc.expected {
+			t.Errorf("%s: expected %v, got %v %q %v", tc.name, tc.expected, status, msg, err)
+		}
+		if status == envexec.StatusAccepted && msg != "" {
+			t.Errorf("%s: expected no message when accepted, got %q", tc.name, msg)
+		}
+		if status != envexec.StatusAccepted && msg == "" {
+			t.Errorf("%s: expected message describing the difference", tc.name)
+		}
+	}
+
+	if _, _, err := compareFiles(openString(""), openString(""), CompareMode(-1), 0); err == nil {
+		t.Error("expected unknown compare mode rejected")
+	}
+}
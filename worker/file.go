@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/lxhcaicai/loj-judge/envexec"
 	"github.com/lxhcaicai/loj-judge/filestore"
+	"io"
 )
 
 // CmdFile 定义命令行中使用的文件
@@ -20,6 +21,8 @@ var (
 	_ CmdFile = &MemoryFile{}
 	_ CmdFile = &CachedFile{}
 	_ CmdFile = &Collector{}
+	_ CmdFile = &ReaderFile{}
+	_ CmdFile = &WriterFile{}
 )
 
 // LocalFile 定义本地文件系统上的文件存储
@@ -83,3 +86,33 @@ func (f *Collector) EnvFile(fs filestore.FileStore) (envexec.File, error) {
 func (f *Collector) String() string {
 	return fmt.Sprintf("collector:(name:%s, max:%d, pipe:%v)", f.Name, f.Max, f.Pipe)
 }
+
+// ReaderFile 定义从 io.Reader 读取的输入，Stream 为 true 时通过管道边读边写入
+type ReaderFile struct {
+	Reader io.Reader
+	Stream bool
+}
+
+// EnvFile 为envexec文件准备文件
+func (f *ReaderFile) EnvFile(fs filestore.FileStore) (envexec.File, error) {
+	return envexec.NewFileReader(f.Reader, f.Stream), nil
+}
+
+func (f *ReaderFile) String() string {
+	return fmt.Sprintf("reader:(stream:%v)", f.Stream)
+}
+
+// WriterFile 定义通过管道实时写入 io.Writer 的输出
+type WriterFile struct {
+	Writer io.Writer
+	Limit  envexec.Size
+}
+
+// EnvFile 为envexec文件准备文件
+func (f *WriterFile) EnvFile(fs filestore.FileStore) (envexec.File, error) {
+	return &envexec.FileWriter{Writer: f.Writer, Limit: f.Limit}, nil
+}
+
+func (f *WriterFile) String() string {
+	return fmt.Sprintf("writer:(limit:%d)", f.Limit)
+}
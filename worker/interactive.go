@@ -0,0 +1,153 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"github.com/lxhcaicai/loj-judge/envexec"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	interactSolution   = 0
+	interactInteractor = 1
+
+	// 选手程序退出后交互器仍未退出，则在该时间后终止交互器
+	interactKillDelay = time.Second
+)
+
+// 交互记录的文件名，分别出现在选手程序和交互器的结果中
+const (
+	InteractSolutionOutput   = "solutionOutput"
+	InteractInteractorOutput = "interactorOutput"
+)
+
+// Interactive 定义交互题的运行方式，选手程序与交互器的标准输入输出相互连接。
+// 交互器以 testlib 的退出码给出结论，标准错误 (fd 2) 上收集的内容作为说明
+type Interactive struct {
+	Transcript      bool // 通过代理记录双方的输出
+	TranscriptLimit Size // 交互记录的最大长度，为 0 时使用 copyOutLimit
+}
+
+func (w *worker) workDoInteractive(ctx context.Context, rc []Cmd, it *Interactive) (rt Response) {
+	if len(rc) != 2 {
+		rt.Error = fmt.Errorf("interactive: expect 2 cmd (solution and interactor), got %d", len(rc))
+		return
+	}
+	cmds := make([]Cmd, len(rc))
+	for i, c := range rc {
+		if len(c.Files) > 0 && c.Files[0] != nil || len(c.Files) > 1 && c.Files[1] != nil {
+			rt.Error = fmt.Errorf("interactive: fd 0 and 1 of cmd %d should be empty", i)
+			return
+		}
+		files := make([]CmdFile, max(len(c.Files), 2))
+		copy(files, c.Files)
+		c.Files = files
+		cmds[i] = c
+	}
+
+	limit := it.TranscriptLimit
+	if limit == 0 {
+		limit = w.copyOutLimit
+	}
+	pm := []PipeMap{
+		{
+			In:  PipeIndex{Index: interactSolution, Fd: 1},
+			Out: PipeIndex{Index: interactInteractor, Fd: 0},
+		},
+		{
+			In:  PipeIndex{Index: interactInteractor, Fd: 1},
+			Out: PipeIndex{Index: interactSolution, Fd: 0},
+		},
+	}
+	if it.Transcript {
+		pm[0].Name, pm[0].Limit, pm[0].Proxy = InteractSolutionOutput, limit, true
+		pm[1].Name, pm[1].Limit, pm[1].Proxy = InteractInteractorOutput, limit, true
+	}
+	// 交互器退出后立即终止选手程序，选手程序退出后给交互器读取剩余内容的时间
+	exitKill := []time.Duration{interactSolution: interactKillDelay, interactInteractor: 0}
+
+	rt = w.workDoGroup(ctx, cmds, pm, exitKill)
+	if rt.Error != nil || len(rt.Results) != 2 {
+		return
+	}
+	inter := &rt.Results[interactInteractor]
+	interactVerdict(&rt.Results[interactSolution], inter, interactorMessage(inter, cmds[interactInteractor]))
+	return
+}
+
+// interactVerdict 根据交互器的结论更新选手程序的结果
+func interactVerdict(sol, inter *Result, msg string) {
+	var (
+		verdict envexec.Status
+		score   float64
+	)
+	switch inter.Status {
+	case envexec.StatusAccepted, envexec.StatusNonzeroExitStatus:
+		// 选手程序提前退出导致交互器读到文件结尾时，以选手程序的失败为准
+		if inter.ExitStatus == checkerExitUnexpectedEOF && sol.Status != envexec.StatusAccepted {
+			return
+		}
+		status, s, m, err := testlibResult(inter.ExitStatus, msg)
+		if err != nil {
+			status, m = envexec.StatusJudgementFailed, err.Error()
+		}
+		// 交互器报告的格式错误为交互协议错误
+		if status == envexec.StatusPresentationError {
+			status = envexec.StatusInvalidInteraction
+		}
+		verdict, score, msg = status, s, m
+
+	default:
+		// 交互器异常退出 (超时、被信号终止等) 时无法给出结论，不论选手程序的结果
+		verdict = envexec.StatusJudgementFailed
+		msg = fmt.Sprintf("interactor: %v", inter.Status)
+		if inter.Error != "" {
+			msg += ": " + inter.Error
+		}
+	}
+
+	switch verdict {
+	case envexec.StatusJudgementFailed:
+		sol.Status = verdict
+		sol.Message = msg
+
+	case envexec.StatusAccepted:
+		if sol.Status == envexec.StatusAccepted {
+			sol.Message = msg
+		}
+
+	default:
+		// 交互器已经给出结论，选手程序因为管道关闭或者被终止而失败时以交互器为准
+		switch sol.Status {
+		case envexec.StatusAccepted, envexec.StatusSignalled, envexec.StatusNonzeroExitStatus:
+			sol.Status = verdict
+			sol.Score = score
+			sol.Message = msg
+		}
+	}
+}
+
+// interactorMessage 读取交互器标准错误上收集的内容
+func interactorMessage(inter *Result, c Cmd) string {
+	if len(c.Files) < 3 {
+		return ""
+	}
+	col, ok := c.Files[2].(*Collector)
+	if !ok {
+		return ""
+	}
+	f, ok := inter.Files[col.Name]
+	if !ok {
+		return ""
+	}
+	return readMessage(f)
+}
+
+// readMessage 读取文件开头的说明而不改变文件偏移
+func readMessage(f *os.File) string {
+	b, _ := io.ReadAll(io.NewSectionReader(f, 0, checkerMessageMax))
+	return strings.TrimSpace(string(b))
+}
@@ -0,0 +1,33 @@
+package worker
+
+import (
+	"github.com/lxhcaicai/loj-judge/envexec"
+	"testing"
+)
+
+func TestInteractVerdict(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		sol      envexec.Status
+		inter    Result
+		expected envexec.Status
+	}{
+		{"accepted", envexec.StatusAccepted, Result{Status: envexec.StatusAccepted}, envexec.StatusAccepted},
+		{"wrong answer", envexec.StatusAccepted, Result{Status: envexec.StatusNonzeroExitStatus, ExitStatus: checkerExitWrongAnswer}, envexec.StatusWrongAnswer},
+		{"protocol error", envexec.StatusAccepted, Result{Status: envexec.StatusNonzeroExitStatus, ExitStatus: checkerExitPresentation}, envexec.StatusInvalidInteraction},
+		{"solution killed by interactor", envexec.StatusSignalled, Result{Status: envexec.StatusNonzeroExitStatus, ExitStatus: checkerExitWrongAnswer}, envexec.StatusWrongAnswer},
+		{"solution exited early", envexec.StatusMemoryLimitExceeded, Result{Status: envexec.StatusNonzeroExitStatus, ExitStatus: checkerExitUnexpectedEOF}, envexec.StatusMemoryLimitExceeded},
+		{"interactor failed", envexec.StatusAccepted, Result{Status: envexec.StatusNonzeroExitStatus, ExitStatus: checkerExitFail}, envexec.StatusJudgementFailed},
+		// 交互器异常退出时不论选手程序的结果都是评测失败
+		{"interactor timeout", envexec.StatusAccepted, Result{Status: envexec.StatusTimeLimitExceeded}, envexec.StatusJudgementFailed},
+		{"interactor timeout with solution timeout", envexec.StatusTimeLimitExceeded, Result{Status: envexec.StatusTimeLimitExceeded}, envexec.StatusJudgementFailed},
+		{"interactor signalled with solution signalled", envexec.StatusSignalled, Result{Status: envexec.StatusSignalled}, envexec.StatusJudgementFailed},
+	} {
+		sol := Result{Status: tc.sol}
+		inter := tc.inter
+		interactVerdict(&sol, &inter, "")
+		if sol.Status != tc.expected {
+			t.Errorf("%s: expected %v, got %v (%s)", tc.name, tc.expected, sol.Status, sol.Message)
+		}
+	}
+}
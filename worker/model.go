@@ -36,6 +36,15 @@ type Cmd struct {
 	CopyOutCached []CmdCopyOutFile
 	CopyOutMax    uint64
 	CopyOutDir    string
+
+	// Compare 在执行成功后比较输出与答案
+	Compare *Compare
+	// Checker 在执行成功后运行评测程序，设置后 Compare 被忽略
+	Checker *Checker
+
+	// Cache 为真时相同输入的单个命令直接返回之前成功的结果 (用于编译)，
+	// 命中时 copyOutCached 文件被复制为新的文件 id，与未命中时一样由请求方删除
+	Cache bool
 }
 
 // Request 定义单个worker请求
@@ -43,6 +52,31 @@ type Request struct {
 	RequestID   string
 	Cmd         []Cmd
 	PipeMapping []PipeMap
+
+	// Priority 为请求的优先级类别，QueueKey 为公平调度使用的队列键 (例如租户或者题目集)
+	Priority Priority
+	QueueKey string
+
+	// Persistent 为真时请求已经由调用者持久化保存 (例如异步任务)，不受等待队列长度的限制
+	Persistent bool
+
+	// QueueTimeout 非零时定义请求在队列中等待的最长时间，超时后返回 ErrQueueTimeout
+	QueueTimeout time.Duration
+
+	// Interactive 非空时以交互题方式运行 Cmd[0] (选手程序) 与 Cmd[1] (交互器)，
+	// 此时 PipeMapping 被忽略
+	Interactive *Interactive
+
+	// ShareEnv 为真时 Cmd 在同一个环境中依次执行，不能与管道同时使用
+	ShareEnv bool
+
+	// Stages 非空时按顺序执行各个阶段，Cmd 等被忽略
+	Stages []Stage
+	// Subtasks 将阶段作为测试点分组计分，不能得分的测试点被提前跳过
+	Subtasks []Subtask
+
+	// Languages 为请求引用的语言预设名称 (已经展开)，转发时用于选择支持这些语言的节点
+	Languages []string
 }
 
 // Result 定义单个命令响应
@@ -50,6 +84,10 @@ type Result struct {
 	Status     envexec.Status
 	ExitStatus int
 	Error      string
+	Message    string  // 比较或评测程序给出的说明
+	Score      float64 // 评测程序给出的部分分
+	Cached     bool    // 结果来自缓存
+	Retry      int     // 因为内部错误使用新的环境重新执行的次数
 	Time       time.Duration
 	RunTime    time.Duration
 	Memory     envexec.Size
@@ -62,5 +100,7 @@ type Result struct {
 type Response struct {
 	RequestID string
 	Results   []Result
+	Stages    []StageResult
+	Verdict   *Verdict // 设置 Subtasks 时给出的最终结论
 	Error     error
 }
@@ -0,0 +1,419 @@
+package worker
+
+import (
+	"sync"
+	"time"
+)
+
+// Priority 定义请求的优先级类别，等待中的高优先级请求总是先于低优先级请求执行
+type Priority int
+
+const (
+	// PriorityLow 用于批量重测等不紧急的请求
+	PriorityLow Priority = iota - 1
+	// PriorityNormal 为默认优先级
+	PriorityNormal
+	// PriorityHigh 用于比赛中的提交等需要尽快返回的请求
+	PriorityHigh
+)
+
+// 按照执行顺序排列的优先级类别
+var priorities = []Priority{PriorityHigh, PriorityNormal, PriorityLow}
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityHigh:
+		return "high"
+	default:
+		return "normal"
+	}
+}
+
+// class 将优先级限制在已定义的类别中
+func (p Priority) class() Priority {
+	return max(PriorityLow, min(PriorityHigh, p))
+}
+
+// QueueStats 定义等待队列以及 worker 的运行状态
+type QueueStats struct {
+	Waiting map[Priority]int    // 各个优先级类别中等待的请求数量
+	Keys    map[string]KeyStats // 有请求等待或者执行中的队列键
+	Memory  Size                // 执行中的请求占用的内存预算
+
+	Parallelism int  // 目标并行数
+	Running     int  // 执行中的请求数量
+	Paused      bool // 暂停从队列中取出请求
+	Draining    bool // 拒绝新的请求，等待已接受的请求完成
+	Drained     bool // 排空完成，可以停止
+}
+
+// KeyStats 定义单个队列键的状态
+type KeyStats struct {
+	Waiting int
+	Running int
+}
+
+// workQueue 按照优先级类别以及队列键调度请求。
+// 同一类别中不同的队列键按照权重公平调度 (stride scheduling)，
+// 同一队列键的请求按照提交顺序执行。
+// 设置内存预算时，下一个请求的内存超过剩余预算则等待执行中的请求完成
+type workQueue struct {
+	weights     map[string]int
+	concurrency map[string]int
+	limit       int  // 等待请求的最大数量
+	budget      Size // 执行中请求的内存之和的上限，为 0 时不限制
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending map[Priority]map[string][]workRequest
+	keys    map[string]*queueKey
+	waiting int     // 不包括持久化的请求
+	total   int     // 等待中的所有请求
+	running int     // 执行中的请求
+	active  int     // 不经过队列执行的请求以及多阶段请求，排空时需要等待
+	vtime   float64 // 最近调度的队列键的进度，新加入的队列键从该进度开始
+	memory  Size    // 执行中的请求的内存之和
+
+	parallelism int // 目标并行数
+	workers     int // 执行请求的 goroutine 数量
+	paused      bool
+	draining    bool
+	drained     chan struct{} // 排空完成后关闭
+	closed      bool
+}
+
+type queueKey struct {
+	waiting int
+	running int
+	pass    float64 // 已调度的请求数量除以权重
+}
+
+func newWorkQueue(limit int, budget Size, weights, concurrency map[string]int) *workQueue {
+	q := &workQueue{
+		weights:     weights,
+		concurrency: concurrency,
+		limit:       limit,
+		budget:      budget,
+		pending:     make(map[Priority]map[string][]workRequest, len(priorities)),
+		keys:        make(map[string]*queueKey),
+	}
+	for _, p := range priorities {
+		q.pending[p] = make(map[string][]workRequest)
+	}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// push 将请求加入等待队列，队列已满、关闭或者排空中不接受新的请求时返回错误。
+// internal 为真时为已接受的多阶段请求中的阶段，排空时仍然接受。
+// 请求设置了等待时间上限时，超时后从队列中移除并返回 ErrQueueTimeout
+func (q *workQueue) push(req workRequest, internal bool) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	switch {
+	case q.closed:
+		return ErrShutdown
+	case q.draining && !internal:
+		return ErrDraining
+	}
+	if !req.Persistent {
+		if q.waiting >= q.limit {
+			return ErrQueueFull
+		}
+		q.waiting++
+	}
+	q.total++
+	k, ok := q.keys[req.QueueKey]
+	if !ok {
+		// 空闲的队列键不能积累进度，避免加入后长时间独占
+		k = &queueKey{pass: q.vtime}
+		q.keys[req.QueueKey] = k
+	}
+	k.waiting++
+	p := req.Priority.class()
+	if req.QueueTimeout > 0 {
+		started := req.started
+		req.timer = time.AfterFunc(req.QueueTimeout, func() {
+			q.expire(p, req.QueueKey, started)
+		})
+	}
+	q.pending[p][req.QueueKey] = append(q.pending[p][req.QueueKey], req)
+	q.cond.Signal()
+	return nil
+}
+
+// pop 等待并取出下一个可以执行的请求。
+// 队列关闭或者 goroutine 数量超过目标并行数时返回 false，调用的 goroutine 应当退出
+func (q *workQueue) pop() (workRequest, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for !q.closed && q.workers <= q.parallelism {
+		if !q.paused || q.draining {
+			if req, ok := q.next(); ok {
+				return req, true
+			}
+		}
+		q.cond.Wait()
+	}
+	q.workers--
+	return workRequest{}, false
+}
+
+// setParallelism 设置目标并行数，goroutine 不足时调用 start 启动。
+// 并行数减少时多余的 goroutine 在完成当前请求后退出
+func (q *workQueue) setParallelism(n int, start func()) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return
+	}
+	q.parallelism = n
+	for ; q.workers < n; q.workers++ {
+		start()
+	}
+	q.cond.Broadcast()
+}
+
+func (q *workQueue) setPaused(paused bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.paused = paused
+	q.cond.Broadcast()
+}
+
+// drain 开始排空，返回在所有已接受的请求完成后关闭的通道。
+// 排空时暂停被忽略，以便队列中的请求能够完成
+func (q *workQueue) drain() <-chan struct{} {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !q.draining {
+		q.draining = true
+		q.drained = make(chan struct{})
+		q.checkDrained()
+	}
+	q.cond.Broadcast()
+	return q.drained
+}
+
+func (q *workQueue) checkDrained() {
+	if !q.draining || q.total > 0 || q.running > 0 || q.active > 0 {
+		return
+	}
+	select {
+	case <-q.drained:
+	default:
+		close(q.drained)
+	}
+}
+
+// begin 以及 end 记录不经过队列执行的请求以及多阶段请求，排空时等待其完成
+func (q *workQueue) begin() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	switch {
+	case q.closed:
+		return ErrShutdown
+	case q.draining:
+		return ErrDraining
+	}
+	q.active++
+	return nil
+}
+
+func (q *workQueue) end() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.active--
+	q.checkDrained()
+}
+
+func (q *workQueue) next() (workRequest, bool) {
+	for _, p := range priorities {
+		var (
+			best  string
+			found bool
+		)
+		for key, reqs := range q.pending[p] {
+			if len(reqs) == 0 || !q.eligible(key) {
+				continue
+			}
+			if !found || q.before(key, best) {
+				best, found = key, true
+			}
+		}
+		if !found {
+			continue
+		}
+
+		// 内存不足时等待，而不是先执行其他较小的请求，以免较大的请求一直无法执行
+		req := q.pending[p][best][0]
+		if !q.fits(req.memory) {
+			return workRequest{}, false
+		}
+		q.remove(p, best, 0)
+		if req.timer != nil {
+			req.timer.Stop()
+		}
+		k := q.keys[best]
+		k.running++
+		q.running++
+		q.vtime = k.pass
+		k.pass += 1 / float64(q.weight(best))
+		q.memory += req.memory
+		return req, true
+	}
+	return workRequest{}, false
+}
+
+// remove 从等待队列中移除请求
+func (q *workQueue) remove(p Priority, key string, i int) workRequest {
+	reqs := q.pending[p][key]
+	req := reqs[i]
+	if len(reqs) == 1 {
+		delete(q.pending[p], key)
+	} else {
+		q.pending[p][key] = append(reqs[:i], reqs[i+1:]...)
+	}
+	q.keys[key].waiting--
+	if !req.Persistent {
+		q.waiting--
+	}
+	q.total--
+	return req
+}
+
+// expire 移除等待超时的请求，请求已经开始执行时不做处理
+func (q *workQueue) expire(p Priority, key string, started chan<- struct{}) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i, req := range q.pending[p][key] {
+		if req.started != started {
+			continue
+		}
+		q.remove(p, key, i)
+		q.release(key)
+		q.checkDrained()
+		close(req.started)
+		req.resultCh <- Response{
+			RequestID: req.RequestID,
+			Error:     ErrQueueTimeout,
+		}
+		return
+	}
+}
+
+// fits 返回请求的内存是否在剩余预算之内，没有请求执行时总是允许执行
+func (q *workQueue) fits(m Size) bool {
+	return q.budget == 0 || q.memory == 0 || q.memory+m <= q.budget
+}
+
+// eligible 返回队列键是否未达到并发上限
+func (q *workQueue) eligible(key string) bool {
+	c := q.concurrency[key]
+	return c <= 0 || q.keys[key].running < c
+}
+
+// before 进度较小的队列键先执行，相同时按照名称排序以保证调度确定
+func (q *workQueue) before(a, b string) bool {
+	pa, pb := q.keys[a].pass, q.keys[b].pass
+	if pa != pb {
+		return pa < pb
+	}
+	return a < b
+}
+
+func (q *workQueue) weight(key string) int {
+	if w := q.weights[key]; w > 0 {
+		return w
+	}
+	return 1
+}
+
+// done 在请求执行完成后调用
+func (q *workQueue) done(req workRequest) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.keys[req.QueueKey].running--
+	q.running--
+	q.memory -= req.memory
+	q.release(req.QueueKey)
+	q.checkDrained()
+	q.cond.Broadcast()
+}
+
+// release 删除空闲的队列键
+func (q *workQueue) release(key string) {
+	if k := q.keys[key]; k.running == 0 && k.waiting == 0 {
+		delete(q.keys, key)
+	}
+}
+
+// close 关闭队列，返回仍在等待的请求
+func (q *workQueue) close() []workRequest {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	q.cond.Broadcast()
+
+	var rt []workRequest
+	for p, keys := range q.pending {
+		for key, reqs := range keys {
+			for _, req := range reqs {
+				if req.timer != nil {
+					req.timer.Stop()
+				}
+				q.keys[key].waiting--
+				rt = append(rt, req)
+			}
+			delete(keys, key)
+		}
+		q.pending[p] = keys
+	}
+	q.waiting, q.total = 0, 0
+	return rt
+}
+
+func (q *workQueue) stats() QueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	s := QueueStats{
+		Waiting: make(map[Priority]int, len(priorities)),
+		Keys:    make(map[string]KeyStats, len(q.keys)),
+		Memory:  q.memory,
+
+		Parallelism: q.parallelism,
+		Running:     q.running,
+		Paused:      q.paused,
+		Draining:    q.draining,
+	}
+	if q.draining {
+		select {
+		case <-q.drained:
+			s.Drained = true
+		default:
+		}
+	}
+	for p, keys := range q.pending {
+		s.Waiting[p] = 0
+		for _, reqs := range keys {
+			s.Waiting[p] += len(reqs)
+		}
+	}
+	for key, k := range q.keys {
+		s.Keys[key] = KeyStats{Waiting: k.waiting, Running: k.running}
+	}
+	return s
+}
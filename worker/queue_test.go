@@ -0,0 +1,135 @@
+package worker
+
+import (
+	"strings"
+	"testing"
+)
+
+// pushRequest 将 id 为 id 的请求加入队列
+func pushRequest(t *testing.T, q *workQueue, id, key string, p Priority, memory Size) workRequest {
+	t.Helper()
+	req := workRequest{
+		Request: &Request{RequestID: id, QueueKey: key, Priority: p},
+		memory:  memory,
+	}
+	if err := q.push(req, false); err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+// nextRequests 依次取出当前可以执行的请求，返回以空格分隔的 id
+func nextRequests(q *workQueue) string {
+	var ids []string
+	for {
+		req, ok := q.next()
+		if !ok {
+			return strings.Join(ids, " ")
+		}
+		ids = append(ids, req.RequestID)
+	}
+}
+
+func TestQueuePriority(t *testing.T) {
+	q := newWorkQueue(10, 0, nil, nil)
+	pushRequest(t, q, "l1", "", PriorityLow, 0)
+	pushRequest(t, q, "n1", "", PriorityNormal, 0)
+	pushRequest(t, q, "l2", "", PriorityLow, 0)
+	pushRequest(t, q, "h1", "", PriorityHigh, 0)
+	pushRequest(t, q, "n2", "", PriorityNormal, 0)
+	// 超出范围的优先级按照最接近的类别处理
+	pushRequest(t, q, "h2", "", Priority(5), 0)
+	pushRequest(t, q, "l3", "", Priority(-5), 0)
+
+	if got := nextRequests(q); got != "h1 h2 n1 n2 l1 l2 l3" {
+		t.Fatalf("expected strict priority order, got %s", got)
+	}
+}
+
+func TestQueueFairness(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		weights  map[string]int
+		expected string
+	}{
+		{"equal weight", nil, "a1 b1 a2 b2 a3 b3 a4"},
+		{"weighted", map[string]int{"a": 2}, "a1 b1 a2 a3 b2 a4 b3"},
+	} {
+		q := newWorkQueue(10, 0, tc.weights, nil)
+		for _, id := range []string{"a1", "a2", "a3", "a4", "b1", "b2", "b3"} {
+			pushRequest(t, q, id, id[:1], PriorityNormal, 0)
+		}
+		if got := nextRequests(q); got != tc.expected {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.expected, got)
+		}
+	}
+
+	// 空闲之后加入的队列键从当前进度开始，不会因为之前空闲而连续执行
+	q := newWorkQueue(10, 0, nil, nil)
+	for _, id := range []string{"a1", "a2", "a3", "a4"} {
+		pushRequest(t, q, id, "a", PriorityNormal, 0)
+	}
+	if got, _ := q.next(); got.RequestID != "a1" {
+		t.Fatalf("expected a1, got %s", got.RequestID)
+	}
+	if got, _ := q.next(); got.RequestID != "a2" {
+		t.Fatalf("expected a2, got %s", got.RequestID)
+	}
+	for _, id := range []string{"b1", "b2"} {
+		pushRequest(t, q, id, "b", PriorityNormal, 0)
+	}
+	if got := nextRequests(q); got != "b1 a3 b2 a4" {
+		t.Fatalf("expected new key interleaved, got %s", got)
+	}
+}
+
+func TestQueueConcurrency(t *testing.T) {
+	q := newWorkQueue(10, 0, nil, map[string]int{"a": 1})
+	pushRequest(t, q, "a1", "a", PriorityNormal, 0)
+	pushRequest(t, q, "a2", "a", PriorityNormal, 0)
+	pushRequest(t, q, "b1", "b", PriorityNormal, 0)
+	pushRequest(t, q, "b2", "b", PriorityNormal, 0)
+
+	// 达到并发上限的队列键让给其他队列键，包括较低优先级的请求
+	pushRequest(t, q, "a3", "a", PriorityHigh, 0)
+	a3, ok := q.next()
+	if !ok || a3.RequestID != "a3" {
+		t.Fatalf("expected a3, got %s", a3.RequestID)
+	}
+	if got := nextRequests(q); got != "b1 b2" {
+		t.Fatalf("expected requests of key a held back, got %s", got)
+	}
+	q.done(a3)
+	if got := nextRequests(q); got != "a1" {
+		t.Fatalf("expected a1 after a3 finished, got %s", got)
+	}
+}
+
+func TestQueueMemoryBudget(t *testing.T) {
+	q := newWorkQueue(10, 10, nil, nil)
+	a1 := pushRequest(t, q, "a1", "a", PriorityNormal, 6)
+	pushRequest(t, q, "a2", "a", PriorityNormal, 6)
+	pushRequest(t, q, "b1", "b", PriorityNormal, 2)
+	pushRequest(t, q, "b2", "b", PriorityNormal, 1)
+
+	// a2 超过剩余预算时等待，不会先执行之后较小的请求
+	if got := nextRequests(q); got != "a1 b1" {
+		t.Fatalf("expected a1 b1 within budget, got %s", got)
+	}
+	q.done(a1)
+	if got := nextRequests(q); got != "a2 b2" {
+		t.Fatalf("expected a2 b2 after a1 finished, got %s", got)
+	}
+
+	// 没有请求执行时，超过预算的请求也可以执行
+	q = newWorkQueue(10, 10, nil, nil)
+	big := pushRequest(t, q, "big", "", PriorityNormal, 20)
+	pushRequest(t, q, "small", "", PriorityNormal, 1)
+	if got := nextRequests(q); got != "big" {
+		t.Fatalf("expected big request executed alone, got %s", got)
+	}
+	q.done(big)
+	if got := nextRequests(q); got != "small" {
+		t.Fatalf("expected small after big finished, got %s", got)
+	}
+}
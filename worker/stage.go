@@ -0,0 +1,304 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"github.com/lxhcaicai/loj-judge/envexec"
+	"github.com/lxhcaicai/loj-judge/filestore"
+	"sync"
+)
+
+// Stage 定义多阶段请求中的一个阶段，每个阶段相当于一次普通的请求
+type Stage struct {
+	Name        string
+	Cmd         []Cmd
+	PipeMapping []PipeMap
+	Interactive *Interactive
+	ShareEnv    bool
+
+	// Parallel 为真时与相邻的并行阶段同时提交执行 (例如多个测试点)
+	Parallel bool
+	// AllowFailure 为真时该阶段失败不会跳过后续阶段
+	AllowFailure bool
+}
+
+// StageResult 定义单个阶段的执行结果
+type StageResult struct {
+	Name    string
+	Results []Result
+	Error   error
+	Skipped bool // 之前的阶段失败或者所属子任务已经失败，该阶段未执行
+}
+
+// StageFile 引用同一请求中之前阶段的输出文件 (copyOut、copyOutCached 或者收集的文件)
+type StageFile struct {
+	Stage string
+	Name  string
+}
+
+// EnvFile 阶段文件需要在执行前被替换为实际文件
+func (f *StageFile) EnvFile(fs filestore.FileStore) (envexec.File, error) {
+	return nil, fmt.Errorf("stage file %s/%s is not resolved", f.Stage, f.Name)
+}
+
+func (f *StageFile) String() string {
+	return fmt.Sprintf("stage:(stage:%s,name:%s)", f.Stage, f.Name)
+}
+
+// submitStages 在后台按顺序提交各个阶段，阶段本身通过工作队列执行，不占用额外的并行数
+func (w *worker) submitStages(ctx context.Context, req *Request, started chan<- struct{}, ch chan<- Response) {
+	var once sync.Once
+	markStarted := func() {
+		once.Do(func() { close(started) })
+	}
+	defer markStarted()
+	defer w.queue.end()
+
+	ch <- w.workDoStages(ctx, req, markStarted, w.submit)
+}
+
+// submitFunc 提交或者直接执行单个阶段的请求
+type submitFunc func(ctx context.Context, req *Request, skip func() bool) (<-chan Response, <-chan struct{})
+
+func (w *worker) workDoStages(ctx context.Context, req *Request, markStarted func(), submit submitFunc) (rt Response) {
+	defer recoverPanic(req.RequestID, &rt)
+
+	rt = Response{RequestID: req.RequestID}
+	if err := checkStages(req.Stages); err != nil {
+		rt.Error = err
+		return rt
+	}
+	var judge *subtaskJudge
+	if len(req.Subtasks) > 0 {
+		var err error
+		if judge, err = newSubtaskJudge(req.Subtasks, req.Stages); err != nil {
+			rt.Error = err
+			return rt
+		}
+	}
+
+	rt.Stages = make([]StageResult, len(req.Stages))
+	outputs := make(map[string][]Result)
+	failed := false
+	for i := 0; i < len(req.Stages); {
+		// 相邻的并行阶段作为一组同时执行
+		j := i + 1
+		if req.Stages[i].Parallel {
+			for j < len(req.Stages) && req.Stages[j].Parallel {
+				j++
+			}
+		}
+		if failed || ctx.Err() != nil {
+			for k := i; k < j; k++ {
+				rt.Stages[k] = StageResult{Name: req.Stages[k].Name, Skipped: true}
+			}
+			i = j
+			continue
+		}
+
+		var wg sync.WaitGroup
+		for k := i; k < j; k++ {
+			wg.Add(1)
+			go func(k int) {
+				defer wg.Done()
+				defer func() {
+					if err := recover(); err != nil {
+						rt.Stages[k] = StageResult{
+							Name:  req.Stages[k].Name,
+							Error: fmt.Errorf("panic during execution: %v", err),
+						}
+					}
+				}()
+				rt.Stages[k] = w.workDoStage(ctx, req, req.Stages[k], outputs, judge, markStarted, submit)
+			}(k)
+		}
+		wg.Wait()
+
+		for k := i; k < j; k++ {
+			s := req.Stages[k]
+			outputs[s.Name] = rt.Stages[k].Results
+			// 测试点失败只影响所属的子任务
+			if judge != nil && judge.inSubtask(s.Name) {
+				continue
+			}
+			if !s.AllowFailure && !stageSucceeded(rt.Stages[k]) {
+				failed = true
+			}
+		}
+		i = j
+	}
+	if judge != nil {
+		rt.Verdict = judge.verdict(req.Stages, rt.Stages)
+	}
+	return rt
+}
+
+func (w *worker) workDoStage(ctx context.Context, req *Request, s Stage, outputs map[string][]Result, judge *subtaskJudge, markStarted func(), submit submitFunc) StageResult {
+	rt := StageResult{Name: s.Name}
+	var skip func() bool
+	if judge != nil && judge.inSubtask(s.Name) {
+		skip = func() bool { return judge.skip(s.Name) }
+		defer func() { judge.update(s.Name, rt, s.Interactive != nil) }()
+	}
+	if skip != nil && skip() {
+		rt.Skipped = true
+		return rt
+	}
+	cmd := make([]Cmd, 0, len(s.Cmd))
+	for _, c := range s.Cmd {
+		c, err := resolveStageCmd(c, outputs)
+		if err != nil {
+			rt.Error = err
+			return rt
+		}
+		cmd = append(cmd, c)
+	}
+
+	resCh, started := submit(ctx, &Request{
+		RequestID:    req.RequestID,
+		Priority:     req.Priority,
+		QueueKey:     req.QueueKey,
+		Persistent:   req.Persistent,
+		QueueTimeout: req.QueueTimeout,
+		Cmd:          cmd,
+		PipeMapping:  s.PipeMapping,
+		Interactive:  s.Interactive,
+		ShareEnv:     s.ShareEnv,
+	}, skip)
+	<-started
+	markStarted()
+	res := <-resCh
+	if res.Error == errSkipped {
+		rt.Skipped = true
+		return rt
+	}
+	rt.Results = res.Results
+	rt.Error = res.Error
+	return rt
+}
+
+func stageSucceeded(r StageResult) bool {
+	if r.Error != nil || r.Skipped {
+		return false
+	}
+	for _, res := range r.Results {
+		if res.Status != envexec.StatusAccepted {
+			return false
+		}
+	}
+	return true
+}
+
+// checkStages 检查阶段名称唯一，并且只引用之前执行完成的阶段
+func checkStages(stages []Stage) error {
+	done := make(map[string]bool)
+	group := make(map[string]bool)
+	for i, s := range stages {
+		if s.Name == "" {
+			return fmt.Errorf("stage #%d: name not provided", i)
+		}
+		if done[s.Name] || group[s.Name] {
+			return fmt.Errorf("stage %s: defined more than once", s.Name)
+		}
+		if len(s.Cmd) == 0 {
+			return fmt.Errorf("stage %s: no cmd provided", s.Name)
+		}
+		if !s.Parallel || i == 0 || !stages[i-1].Parallel {
+			for n := range group {
+				done[n] = true
+			}
+			group = make(map[string]bool)
+		}
+		for _, c := range s.Cmd {
+			for _, f := range stageFiles(c) {
+				if !done[f.Stage] {
+					return fmt.Errorf("stage %s: reference to stage %s which is not finished before", s.Name, f.Stage)
+				}
+			}
+		}
+		group[s.Name] = true
+	}
+	return nil
+}
+
+func stageFiles(c Cmd) []*StageFile {
+	var rt []*StageFile
+	add := func(f CmdFile) {
+		if sf, ok := f.(*StageFile); ok {
+			rt = append(rt, sf)
+		}
+	}
+	for _, f := range c.Files {
+		add(f)
+	}
+	for _, f := range c.CopyIn {
+		add(f)
+	}
+	if c.Compare != nil {
+		add(c.Compare.Answer)
+	}
+	if c.Checker != nil {
+		add(c.Checker.Input)
+		add(c.Checker.Answer)
+		rt = append(rt, stageFiles(c.Checker.Cmd)...)
+	}
+	return rt
+}
+
+// resolveStageCmd 将命令中引用的阶段文件替换为之前阶段的输出
+func resolveStageCmd(c Cmd, outputs map[string][]Result) (Cmd, error) {
+	var err error
+	resolve := func(f CmdFile) CmdFile {
+		sf, ok := f.(*StageFile)
+		if !ok || err != nil {
+			return f
+		}
+		var rf CmdFile
+		rf, err = resolveStageFile(sf, outputs)
+		return rf
+	}
+
+	files := make([]CmdFile, 0, len(c.Files))
+	for _, f := range c.Files {
+		files = append(files, resolve(f))
+	}
+	c.Files = files
+	if c.CopyIn != nil {
+		copyIn := make(map[string]CmdFile, len(c.CopyIn))
+		for k, f := range c.CopyIn {
+			copyIn[k] = resolve(f)
+		}
+		c.CopyIn = copyIn
+	}
+	if c.Compare != nil {
+		cmp := *c.Compare
+		cmp.Answer = resolve(cmp.Answer)
+		c.Compare = &cmp
+	}
+	if c.Checker != nil {
+		chk := *c.Checker
+		chk.Input = resolve(chk.Input)
+		chk.Answer = resolve(chk.Answer)
+		if err == nil {
+			chk.Cmd, err = resolveStageCmd(chk.Cmd, outputs)
+		}
+		c.Checker = &chk
+	}
+	return c, err
+}
+
+func resolveStageFile(f *StageFile, outputs map[string][]Result) (CmdFile, error) {
+	results, ok := outputs[f.Stage]
+	if !ok {
+		return nil, fmt.Errorf("stage %s: not found", f.Stage)
+	}
+	for _, r := range results {
+		if of, ok := r.Files[f.Name]; ok {
+			return &LocalFile{Src: of.Name()}, nil
+		}
+		if id, ok := r.FileIDs[f.Name]; ok {
+			return &CachedFile{FileID: id}, nil
+		}
+	}
+	return nil, fmt.Errorf("stage %s: file %s not found", f.Stage, f.Name)
+}
@@ -0,0 +1,75 @@
+package worker
+
+import "testing"
+
+// stageCmd 返回以 refs 中的阶段文件作为输入的命令
+func stageCmd(refs ...string) []Cmd {
+	c := Cmd{Args: []string{"a.out"}, CopyIn: make(map[string]CmdFile)}
+	for _, r := range refs {
+		c.CopyIn[r] = &StageFile{Stage: r, Name: "out"}
+	}
+	return []Cmd{c}
+}
+
+func TestCheckStages(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		stages []Stage
+		ok     bool
+	}{
+		{"sequential reference", []Stage{
+			{Name: "compile", Cmd: stageCmd()},
+			{Name: "run", Cmd: stageCmd("compile")},
+		}, true},
+		{"parallel stages reference earlier stage", []Stage{
+			{Name: "compile", Cmd: stageCmd()},
+			{Name: "t1", Cmd: stageCmd("compile"), Parallel: true},
+			{Name: "t2", Cmd: stageCmd("compile"), Parallel: true},
+			{Name: "sum", Cmd: stageCmd("t1", "t2")},
+		}, true},
+		{"name not provided", []Stage{{Cmd: stageCmd()}}, false},
+		{"duplicated name", []Stage{
+			{Name: "a", Cmd: stageCmd()},
+			{Name: "a", Cmd: stageCmd()},
+		}, false},
+		{"no cmd", []Stage{{Name: "a"}}, false},
+		{"reference to later stage", []Stage{
+			{Name: "run", Cmd: stageCmd("compile")},
+			{Name: "compile", Cmd: stageCmd()},
+		}, false},
+		{"reference to itself", []Stage{{Name: "a", Cmd: stageCmd("a")}}, false},
+		{"reference within parallel group", []Stage{
+			{Name: "t1", Cmd: stageCmd(), Parallel: true},
+			{Name: "t2", Cmd: stageCmd("t1"), Parallel: true},
+		}, false},
+		{"reference in checker", []Stage{
+			{Name: "run", Cmd: []Cmd{{Args: []string{"a.out"}, Checker: &Checker{Cmd: stageCmd("compile")[0]}}}},
+		}, false},
+	} {
+		if err := checkStages(tc.stages); (err == nil) != tc.ok {
+			t.Errorf("%s: expected ok %v, got %v", tc.name, tc.ok, err)
+		}
+	}
+}
+
+func TestResolveStageCmd(t *testing.T) {
+	outputs := map[string][]Result{
+		"compile": {{FileIDs: map[string]string{"out": "id"}}},
+	}
+	c, err := resolveStageCmd(stageCmd("compile")[0], outputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f, ok := c.CopyIn["compile"].(*CachedFile); !ok || f.FileID != "id" {
+		t.Fatalf("expected cached file id, got %v", c.CopyIn["compile"])
+	}
+
+	for _, refs := range [][]string{{"missing"}, {"compile", "missing"}} {
+		if _, err := resolveStageCmd(stageCmd(refs...)[0], outputs); err == nil {
+			t.Errorf("%v: expected unresolved stage file rejected", refs)
+		}
+	}
+	if _, err := resolveStageCmd(Cmd{CopyIn: map[string]CmdFile{"in": &StageFile{Stage: "compile", Name: "other"}}}, outputs); err == nil {
+		t.Error("expected missing stage output rejected")
+	}
+}
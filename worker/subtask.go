@@ -0,0 +1,281 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"github.com/lxhcaicai/loj-judge/envexec"
+	"math"
+	"sync"
+)
+
+// ScorePolicy 定义子任务的计分方式
+type ScorePolicy int
+
+const (
+	// ScoreSum 按照测试点得分的平均值计分
+	ScoreSum ScorePolicy = iota
+	// ScoreMin 按照测试点得分的最小值计分
+	ScoreMin
+	// ScoreAllOrNothing 所有测试点通过时得到满分，否则不得分
+	ScoreAllOrNothing
+)
+
+// errSkipped 表示阶段在执行前因为子任务已经失败而被跳过
+var errSkipped = errors.New("skipped")
+
+// Subtask 定义由若干测试点阶段组成的子任务。
+// 测试点通过得到满分，部分正确按照评测程序给出的比例 (0 到 1) 得分
+type Subtask struct {
+	Name         string
+	Score        float64 // 子任务满分
+	Policy       ScorePolicy
+	Stages       []string // 测试点对应的阶段名称
+	Dependencies []string // 依赖的子任务，依赖未满分时该子任务不得分并跳过
+}
+
+// SubtaskResult 定义子任务的得分
+type SubtaskResult struct {
+	Name    string
+	Status  envexec.Status // 已执行测试点中最差的状态
+	Score   float64
+	Skipped bool // 依赖未满分或者所有测试点均被跳过
+}
+
+// Verdict 定义多阶段请求的最终结论
+type Verdict struct {
+	Status   envexec.Status // 已执行阶段中最差的状态
+	Score    float64
+	Subtasks []SubtaskResult
+}
+
+// subtaskJudge 记录执行过程中子任务的状态，用于提前跳过不会得分的测试点
+type subtaskJudge struct {
+	subtasks []Subtask
+	index    map[string]int   // 子任务名称 -> 下标
+	stageOf  map[string][]int // 阶段名称 -> 所属子任务
+
+	mu     sync.Mutex
+	failed []bool // 子任务中有测试点未通过
+}
+
+func newSubtaskJudge(subtasks []Subtask, stages []Stage) (*subtaskJudge, error) {
+	j := &subtaskJudge{
+		subtasks: subtasks,
+		index:    make(map[string]int, len(subtasks)),
+		stageOf:  make(map[string][]int),
+		failed:   make([]bool, len(subtasks)),
+	}
+	stageNames := make(map[string]bool, len(stages))
+	for _, s := range stages {
+		stageNames[s.Name] = true
+	}
+	for i, s := range subtasks {
+		if s.Name == "" {
+			return nil, fmt.Errorf("subtask #%d: name not provided", i)
+		}
+		if _, ok := j.index[s.Name]; ok {
+			return nil, fmt.Errorf("subtask %s: defined more than once", s.Name)
+		}
+		switch s.Policy {
+		case ScoreSum, ScoreMin, ScoreAllOrNothing:
+		default:
+			return nil, fmt.Errorf("subtask %s: unknown score policy %d", s.Name, s.Policy)
+		}
+		if len(s.Stages) == 0 {
+			return nil, fmt.Errorf("subtask %s: no stage provided", s.Name)
+		}
+		for _, n := range s.Stages {
+			if !stageNames[n] {
+				return nil, fmt.Errorf("subtask %s: stage %s not found", s.Name, n)
+			}
+			j.stageOf[n] = append(j.stageOf[n], i)
+		}
+		j.index[s.Name] = i
+	}
+	for _, s := range subtasks {
+		for _, d := range s.Dependencies {
+			if _, ok := j.index[d]; !ok {
+				return nil, fmt.Errorf("subtask %s: dependency %s not found", s.Name, d)
+			}
+		}
+	}
+	if err := j.checkCycle(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// checkCycle 检查子任务之间的依赖不存在环
+func (j *subtaskJudge) checkCycle() error {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(j.subtasks))
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visiting:
+			return fmt.Errorf("subtask %s: circular dependency", j.subtasks[i].Name)
+		case visited:
+			return nil
+		}
+		state[i] = visiting
+		for _, d := range j.subtasks[i].Dependencies {
+			if err := visit(j.index[d]); err != nil {
+				return err
+			}
+		}
+		state[i] = visited
+		return nil
+	}
+	for i := range j.subtasks {
+		if err := visit(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inSubtask 返回阶段是否为子任务的测试点
+func (j *subtaskJudge) inSubtask(stage string) bool {
+	return len(j.stageOf[stage]) > 0
+}
+
+// skip 返回测试点是否可以跳过，即所属的所有子任务都不可能再得分
+func (j *subtaskJudge) skip(stage string) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	sub := j.stageOf[stage]
+	if len(sub) == 0 {
+		return false
+	}
+	for _, i := range sub {
+		if !j.dead(i) {
+			return false
+		}
+	}
+	return true
+}
+
+// dead 子任务的依赖未满分，或者按最小值 / 全部通过计分时已有测试点未通过
+func (j *subtaskJudge) dead(i int) bool {
+	if j.dependencyFailed(i) {
+		return true
+	}
+	return j.failed[i] && j.subtasks[i].Policy != ScoreSum
+}
+
+func (j *subtaskJudge) dependencyFailed(i int) bool {
+	for _, d := range j.subtasks[i].Dependencies {
+		di := j.index[d]
+		if j.failed[di] || j.dependencyFailed(di) {
+			return true
+		}
+	}
+	return false
+}
+
+// update 根据测试点结果更新子任务状态
+func (j *subtaskJudge) update(stage string, r StageResult, interactive bool) {
+	if ratio, _ := stageScore(r, interactive); ratio >= 1 {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, i := range j.stageOf[stage] {
+		j.failed[i] = true
+	}
+}
+
+// verdict 计算子任务得分以及最终结论
+func (j *subtaskJudge) verdict(stages []Stage, results []StageResult) *Verdict {
+	byName := make(map[string]int, len(stages))
+	for i, s := range stages {
+		byName[s.Name] = i
+	}
+
+	v := &Verdict{Status: envexec.StatusInvalid}
+	for i, r := range results {
+		if _, st := stageScore(r, stages[i].Interactive != nil); st != envexec.StatusInvalid {
+			v.Status = worseStatus(v.Status, st)
+		}
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for i, s := range j.subtasks {
+		sr := SubtaskResult{Name: s.Name, Status: envexec.StatusInvalid}
+		if j.dependencyFailed(i) {
+			sr.Skipped = true
+			v.Subtasks = append(v.Subtasks, sr)
+			continue
+		}
+
+		sum, min := 0.0, 1.0
+		executed := 0
+		for _, n := range s.Stages {
+			k := byName[n]
+			ratio, st := stageScore(results[k], stages[k].Interactive != nil)
+			if st != envexec.StatusInvalid {
+				executed++
+				sr.Status = worseStatus(sr.Status, st)
+			}
+			sum += ratio
+			min = math.Min(min, ratio)
+		}
+		sr.Skipped = executed == 0
+
+		switch s.Policy {
+		case ScoreSum:
+			sr.Score = s.Score * sum / float64(len(s.Stages))
+		case ScoreMin:
+			sr.Score = s.Score * min
+		case ScoreAllOrNothing:
+			if min >= 1 {
+				sr.Score = s.Score
+			}
+		}
+		v.Score += sr.Score
+		v.Subtasks = append(v.Subtasks, sr)
+	}
+	return v
+}
+
+// stageScore 返回测试点的得分比例以及状态，未执行时状态为 StatusInvalid。
+// 交互题以选手程序的结果为准，其他情况取所有命令中最差的状态
+func stageScore(r StageResult, interactive bool) (float64, envexec.Status) {
+	switch {
+	case r.Skipped:
+		return 0, envexec.StatusInvalid
+	case r.Error != nil || len(r.Results) == 0:
+		return 0, envexec.StatusInternalError
+	}
+	results := r.Results
+	if interactive {
+		results = results[:1]
+	}
+	var status envexec.Status = envexec.StatusInvalid
+	ratio := 1.0
+	for _, res := range results {
+		status = worseStatus(status, res.Status)
+		switch res.Status {
+		case envexec.StatusAccepted:
+		case envexec.StatusPartiallyCorrect:
+			ratio = math.Min(ratio, math.Max(0, math.Min(1, res.Score)))
+		default:
+			ratio = 0
+		}
+	}
+	return ratio, status
+}
+
+// worseStatus 按照 envexec.Status 的顺序返回较差的状态
+func worseStatus(a, b envexec.Status) envexec.Status {
+	if b > a {
+		return b
+	}
+	return a
+}
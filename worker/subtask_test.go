@@ -0,0 +1,170 @@
+package worker
+
+import (
+	"errors"
+	"github.com/lxhcaicai/loj-judge/envexec"
+	"math"
+	"testing"
+)
+
+func TestWorseStatus(t *testing.T) {
+	for _, tc := range []struct {
+		a, b, expected envexec.Status
+	}{
+		{envexec.StatusInvalid, envexec.StatusAccepted, envexec.StatusAccepted},
+		{envexec.StatusAccepted, envexec.StatusInvalid, envexec.StatusAccepted},
+		{envexec.StatusAccepted, envexec.StatusWrongAnswer, envexec.StatusWrongAnswer},
+		{envexec.StatusWrongAnswer, envexec.StatusPartiallyCorrect, envexec.StatusPartiallyCorrect},
+		{envexec.StatusTimeLimitExceeded, envexec.StatusWrongAnswer, envexec.StatusTimeLimitExceeded},
+		{envexec.StatusJudgementFailed, envexec.StatusSignalled, envexec.StatusJudgementFailed},
+		{envexec.StatusInternalError, envexec.StatusJudgementFailed, envexec.StatusInternalError},
+		{envexec.StatusAccepted, envexec.StatusAccepted, envexec.StatusAccepted},
+	} {
+		if got := worseStatus(tc.a, tc.b); got != tc.expected {
+			t.Errorf("worseStatus(%v, %v): expected %v, got %v", tc.a, tc.b, tc.expected, got)
+		}
+	}
+}
+
+// testStages 返回名称为 names 的阶段
+func testStages(names ...string) []Stage {
+	stages := make([]Stage, 0, len(names))
+	for _, n := range names {
+		stages = append(stages, Stage{Name: n, Cmd: []Cmd{{Args: []string{"a.out"}}}})
+	}
+	return stages
+}
+
+func stageResult(name string, status envexec.Status, score float64) StageResult {
+	return StageResult{Name: name, Results: []Result{{Status: status, Score: score}}}
+}
+
+func TestNewSubtaskJudge(t *testing.T) {
+	stages := testStages("1", "2")
+	for _, tc := range []struct {
+		name     string
+		subtasks []Subtask
+		ok       bool
+	}{
+		{"valid", []Subtask{{Name: "a", Stages: []string{"1"}}, {Name: "b", Stages: []string{"2"}, Dependencies: []string{"a"}}}, true},
+		{"name not provided", []Subtask{{Stages: []string{"1"}}}, false},
+		{"duplicated name", []Subtask{{Name: "a", Stages: []string{"1"}}, {Name: "a", Stages: []string{"2"}}}, false},
+		{"unknown policy", []Subtask{{Name: "a", Stages: []string{"1"}, Policy: ScorePolicy(10)}}, false},
+		{"no stage", []Subtask{{Name: "a"}}, false},
+		{"unknown stage", []Subtask{{Name: "a", Stages: []string{"3"}}}, false},
+		{"unknown dependency", []Subtask{{Name: "a", Stages: []string{"1"}, Dependencies: []string{"b"}}}, false},
+		{"self dependency", []Subtask{{Name: "a", Stages: []string{"1"}, Dependencies: []string{"a"}}}, false},
+		{"dependency cycle", []Subtask{
+			{Name: "a", Stages: []string{"1"}, Dependencies: []string{"b"}},
+			{Name: "b", Stages: []string{"2"}, Dependencies: []string{"a"}},
+		}, false},
+	} {
+		if _, err := newSubtaskJudge(tc.subtasks, stages); (err == nil) != tc.ok {
+			t.Errorf("%s: expected ok %v, got %v", tc.name, tc.ok, err)
+		}
+	}
+}
+
+func TestSubtaskScore(t *testing.T) {
+	stages := testStages("1", "2")
+	results := []StageResult{
+		stageResult("1", envexec.StatusAccepted, 0),
+		stageResult("2", envexec.StatusPartiallyCorrect, 0.5),
+	}
+	for _, tc := range []struct {
+		policy   ScorePolicy
+		expected float64
+	}{
+		{ScoreSum, 75},
+		{ScoreMin, 50},
+		{ScoreAllOrNothing, 0},
+	} {
+		j, err := newSubtaskJudge([]Subtask{{Name: "a", Score: 100, Policy: tc.policy, Stages: []string{"1", "2"}}}, stages)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v := j.verdict(stages, results)
+		if math.Abs(v.Score-tc.expected) > 1e-9 || len(v.Subtasks) != 1 || v.Subtasks[0].Score != v.Score {
+			t.Errorf("policy %d: expected score %v, got %+v", tc.policy, tc.expected, v)
+		}
+		if v.Status != envexec.StatusPartiallyCorrect || v.Subtasks[0].Status != envexec.StatusPartiallyCorrect {
+			t.Errorf("policy %d: expected partially correct, got %+v", tc.policy, v)
+		}
+	}
+}
+
+func TestStageScore(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		result      StageResult
+		interactive bool
+		ratio       float64
+		status      envexec.Status
+	}{
+		{"accepted", stageResult("1", envexec.StatusAccepted, 0), false, 1, envexec.StatusAccepted},
+		{"partially correct", stageResult("1", envexec.StatusPartiallyCorrect, 0.3), false, 0.3, envexec.StatusPartiallyCorrect},
+		{"score clamped", stageResult("1", envexec.StatusPartiallyCorrect, 2), false, 1, envexec.StatusPartiallyCorrect},
+		{"wrong answer", stageResult("1", envexec.StatusWrongAnswer, 0.5), false, 0, envexec.StatusWrongAnswer},
+		{"skipped", StageResult{Skipped: true}, false, 0, envexec.StatusInvalid},
+		{"error", StageResult{Error: errors.New("failed")}, false, 0, envexec.StatusInternalError},
+		{"worst command", StageResult{Results: []Result{{Status: envexec.StatusAccepted}, {Status: envexec.StatusTimeLimitExceeded}}}, false, 0, envexec.StatusTimeLimitExceeded},
+		// 交互题只看选手程序的结果
+		{"interactive", StageResult{Results: []Result{{Status: envexec.StatusAccepted}, {Status: envexec.StatusNonzeroExitStatus}}}, true, 1, envexec.StatusAccepted},
+	} {
+		ratio, status := stageScore(tc.result, tc.interactive)
+		if ratio != tc.ratio || status != tc.status {
+			t.Errorf("%s: expected %v %v, got %v %v", tc.name, tc.ratio, tc.status, ratio, status)
+		}
+	}
+}
+
+func TestSubtaskSkip(t *testing.T) {
+	stages := testStages("1", "2", "3", "4", "5")
+	j, err := newSubtaskJudge([]Subtask{
+		{Name: "a", Score: 20, Policy: ScoreMin, Stages: []string{"1", "2"}},
+		{Name: "b", Score: 30, Policy: ScoreSum, Stages: []string{"3"}, Dependencies: []string{"a"}},
+		{Name: "c", Score: 50, Policy: ScoreSum, Stages: []string{"4"}},
+	}, stages)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := make([]StageResult, len(stages))
+	run := func(i int, status envexec.Status) {
+		results[i] = stageResult(stages[i].Name, status, 0)
+		j.update(stages[i].Name, results[i], false)
+	}
+	if j.skip("1") || j.skip("3") || j.skip("5") {
+		t.Fatal("expected no stage skipped before any failure")
+	}
+	run(0, envexec.StatusWrongAnswer)
+
+	// 按最小值计分的子任务以及依赖它的子任务不可能再得分
+	for _, s := range []string{"2", "3"} {
+		if !j.skip(s) {
+			t.Errorf("expected stage %s skipped", s)
+		}
+	}
+	results[1] = StageResult{Name: "2", Skipped: true}
+	results[2] = StageResult{Name: "3", Skipped: true}
+	// 不属于失败子任务的测试点以及不属于任何子任务的阶段仍然执行
+	if j.skip("4") || j.skip("5") {
+		t.Fatal("expected stages of other subtasks executed")
+	}
+	run(3, envexec.StatusAccepted)
+	run(4, envexec.StatusAccepted)
+
+	v := j.verdict(stages, results)
+	if v.Score != 50 || v.Status != envexec.StatusWrongAnswer || len(v.Subtasks) != 3 {
+		t.Fatalf("unexpected verdict: %+v", v)
+	}
+	if a := v.Subtasks[0]; a.Score != 0 || a.Skipped || a.Status != envexec.StatusWrongAnswer {
+		t.Errorf("unexpected subtask a: %+v", a)
+	}
+	if b := v.Subtasks[1]; b.Score != 0 || !b.Skipped {
+		t.Errorf("expected subtask b skipped, got %+v", b)
+	}
+	if c := v.Subtasks[2]; c.Score != 50 || c.Skipped || c.Status != envexec.StatusAccepted {
+		t.Errorf("unexpected subtask c: %+v", c)
+	}
+}
@@ -2,21 +2,48 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/lxhcaicai/loj-judge/envexec"
 	"github.com/lxhcaicai/loj-judge/filestore"
 	"os"
 	"path"
+	"strings"
 	"sync"
 	"time"
 )
 
 const maxWaiting = 512
 
+// 容器中无法执行用户程序 (例如可执行文件不存在或者格式错误) 时的错误前缀。
+// 与容器通信失败同样以 execve: 开头，但是后者说明环境已经损坏
+var execveUserErrorPrefixes = []string{
+	"execve: execve: start: ",
+	"execve: handle: ",
+}
+
+// 容器中处理 execve 时不是由用户程序导致的错误
+var execveInternalErrors = []string{
+	"execve: handle: no parameter provided",
+	"execve: handle: expected fexecve fd",
+}
+
+var (
+	// ErrQueueFull 表示等待队列已满，请求没有被执行
+	ErrQueueFull = errors.New("worker queue is full")
+	// ErrQueueTimeout 表示请求等待执行的时间超过了 QueueTimeout
+	ErrQueueTimeout = errors.New("queue timeout")
+	// ErrDraining 表示 worker 正在排空，不再接受新的请求
+	ErrDraining = errors.New("worker is draining and not accepting new requests")
+	// ErrShutdown 表示 worker 已经停止，请求没有被执行
+	ErrShutdown = errors.New("worker is shut down")
+)
+
 // EnvironmentPool 定义用于执行命令的环境池
 type EnvironmentPool interface {
 	Get() (envexec.Environment, error)
 	Put(envexec.Environment)
+	Destroy(envexec.Environment) // 销毁可能已经损坏的环境，不放回环境池
 }
 
 // Config 定义 worker 配置
@@ -31,6 +58,24 @@ type Config struct {
 	CopyOutLimit          envexec.Size
 	OpenFileLimit         uint64
 	ExecObserver          func(Response)
+
+	// 结果缓存的条目数量 (为 0 时不缓存) 以及有效时间
+	ResultCacheSize int
+	ResultCacheTTL  time.Duration
+
+	// 出现内部错误 (例如容器或者 cgroup 失败) 时使用新的环境重新执行的最大次数
+	MaxRetry int
+
+	// 队列键的调度权重 (默认为 1) 以及同时执行的请求数量上限 (为 0 时不限制)
+	QueueWeights     map[string]int
+	QueueConcurrency map[string]int
+
+	// 同时执行的请求的内存限制 (包括 ExtraMemoryLimit) 之和的上限，为 0 时不限制
+	MemoryBudget envexec.Size
+
+	// Executor 非空时请求 (包括多阶段请求) 整体交给 Executor 执行而不使用本地的环境，
+	// 例如分发到集群中的节点。此时并行数为同时分发的请求数量
+	Executor func(context.Context, *Request) Response
 }
 
 // Worker 为执行器定义接口
@@ -38,6 +83,17 @@ type Worker interface {
 	Start()
 	Submit(context.Context, *Request) (<-chan Response, <-chan struct{})
 	Execute(context.Context, *Request) <-chan Response
+	QueueStats() QueueStats
+
+	// SetParallelism 在运行时修改并行数
+	SetParallelism(int)
+	// Pause 以及 Resume 暂停以及恢复从队列中取出请求，暂停时仍然接受新的请求
+	Pause()
+	Resume()
+	// Drain 拒绝之后的新请求并返回在已接受的请求全部完成后关闭的通道
+	Drain() <-chan struct{}
+
+	// Shutdown 停止 worker，仍在等待的请求返回 ErrShutdown
 	Shutdown()
 }
 
@@ -55,17 +111,22 @@ type worker struct {
 	openFileLimit         uint64
 
 	execObserver func(Response)
+	cache        *resultCache
+	maxRetry     int
+	executor     func(context.Context, *Request) Response
 
 	startOne sync.Once
 	stopOne  sync.Once
 	wg       sync.WaitGroup
-	workCh   chan workRequest
-	done     chan struct{}
+	queue    *workQueue
 }
 
 type workRequest struct {
 	*Request
 	context.Context
+	skip     func() bool
+	memory   Size        // 执行时占用的内存预算
+	timer    *time.Timer // 等待超时的计时器
 	started  chan<- struct{}
 	resultCh chan<- Response
 }
@@ -83,49 +144,130 @@ func New(conf Config) Worker {
 		copyOutLimit:          conf.CopyOutLimit,
 		openFileLimit:         conf.OpenFileLimit,
 		execObserver:          conf.ExecObserver,
+		cache:                 newResultCache(conf.ResultCacheSize, conf.ResultCacheTTL, conf.FileStore),
+		maxRetry:              conf.MaxRetry,
+		executor:              conf.Executor,
+		queue:                 newWorkQueue(maxWaiting, conf.MemoryBudget, conf.QueueWeights, conf.QueueConcurrency),
 	}
 }
 
 // Start 以给定的并行数启动worker循环
 func (w *worker) Start() {
 	w.startOne.Do(func() {
-		w.workCh = make(chan workRequest, maxWaiting)
-		w.done = make(chan struct{})
-		w.wg.Add(w.parallelism)
-		for i := 0; i < w.parallelism; i++ {
-			go w.loop()
-		}
+		w.SetParallelism(w.parallelism)
+	})
+}
+
+func (w *worker) SetParallelism(n int) {
+	w.queue.setParallelism(n, func() {
+		w.wg.Add(1)
+		go w.loop()
 	})
 }
 
+func (w *worker) Pause() {
+	w.queue.setPaused(true)
+}
+
+func (w *worker) Resume() {
+	w.queue.setPaused(false)
+}
+
+func (w *worker) Drain() <-chan struct{} {
+	return w.queue.drain()
+}
+
 func (w *worker) Submit(ctx context.Context, req *Request) (<-chan Response, <-chan struct{}) {
+	if len(req.Stages) > 0 && w.executor == nil {
+		if err := w.queue.begin(); err != nil {
+			return errorResponse(req, err)
+		}
+		ch := make(chan Response, 1)
+		started := make(chan struct{})
+		go w.submitStages(ctx, req, started, ch)
+		return ch, started
+	}
+	return w.push(ctx, req, nil, false)
+}
+
+// submit 将多阶段请求中的阶段放入工作队列，skip 非空并且在开始执行前返回真时跳过该请求
+func (w *worker) submit(ctx context.Context, req *Request, skip func() bool) (<-chan Response, <-chan struct{}) {
+	return w.push(ctx, req, skip, true)
+}
+
+func (w *worker) push(ctx context.Context, req *Request, skip func() bool, internal bool) (<-chan Response, <-chan struct{}) {
 	ch := make(chan Response, 1)
 	started := make(chan struct{})
-	select {
-	case w.workCh <- workRequest{
+	if err := w.queue.push(workRequest{
 		Request:  req,
 		Context:  ctx,
+		skip:     skip,
+		memory:   w.requestMemory(req),
 		started:  started,
 		resultCh: ch,
-	}:
-	default:
-		close(started)
-		ch <- Response{
-			RequestID: req.RequestID,
-			Error:     fmt.Errorf("worker queue is full"),
-		}
+	}, internal); err != nil {
+		return errorResponse(req, err)
+	}
+	return ch, started
+}
+
+// errorResponse 返回未执行的请求的结果
+func errorResponse(req *Request, err error) (<-chan Response, <-chan struct{}) {
+	ch := make(chan Response, 1)
+	started := make(chan struct{})
+	close(started)
+	ch <- Response{
+		RequestID: req.RequestID,
+		Error:     err,
 	}
 	return ch, started
 }
 
-func (w worker) Execute(ctx context.Context, request *Request) <-chan Response {
-	//TODO implement me
-	panic("implement me")
+// Execute 在当前 goroutine 中直接执行请求，不经过等待队列也不受并行数限制，
+// 返回的通道中已经包含结果。多阶段请求的各个阶段同样直接执行
+func (w *worker) Execute(ctx context.Context, req *Request) <-chan Response {
+	if err := w.queue.begin(); err != nil {
+		ch, _ := errorResponse(req, err)
+		return ch
+	}
+	defer w.queue.end()
+
+	if len(req.Stages) > 0 && w.executor == nil {
+		ch := make(chan Response, 1)
+		ch <- w.workDoStages(ctx, req, func() {}, w.execute)
+		return ch
+	}
+	ch, _ := w.execute(ctx, req, nil)
+	return ch
+}
+
+// execute 直接执行请求，与 submit 的形式相同以便多阶段请求使用
+func (w *worker) execute(ctx context.Context, req *Request, skip func() bool) (<-chan Response, <-chan struct{}) {
+	ch := make(chan Response, 1)
+	started := make(chan struct{})
+	close(started)
+	ch <- w.workDo(workRequest{
+		Request: req,
+		Context: ctx,
+		skip:    skip,
+	})
+	return ch, started
+}
+
+// QueueStats 返回等待队列的状态
+func (w *worker) QueueStats() QueueStats {
+	return w.queue.stats()
 }
 
 func (w *worker) Shutdown() {
 	w.stopOne.Do(func() {
-		close(w.done)
+		for _, req := range w.queue.close() {
+			close(req.started)
+			req.resultCh <- Response{
+				RequestID: req.RequestID,
+				Error:     ErrShutdown,
+			}
+		}
 		w.wg.Wait()
 	})
 }
@@ -133,34 +275,48 @@ func (w *worker) Shutdown() {
 func (w *worker) loop() {
 	defer w.wg.Done()
 	for {
-		select {
-		case req, ok := <-w.workCh:
-			if !ok {
-				return
-			}
-			close(req.started)
-
-			select {
-			case <-req.Context.Done():
-				req.resultCh <- Response{
-					RequestID: req.RequestID,
-					Error:     fmt.Errorf("cancelled before execute"),
-				}
-			default:
-				req.resultCh <- w.workDoCmd(req.Context, req.Request)
-			}
-		case <-w.done:
+		req, ok := w.queue.pop()
+		if !ok {
 			return
 		}
+		close(req.started)
+		req.resultCh <- w.workDo(req)
+		w.queue.done(req)
 	}
 }
 
+func (w *worker) workDo(req workRequest) (rt Response) {
+	defer recoverPanic(req.RequestID, &rt)
+
+	select {
+	case <-req.Context.Done():
+		return Response{
+			RequestID: req.RequestID,
+			Error:     fmt.Errorf("cancelled before execute"),
+		}
+	default:
+	}
+	if req.skip != nil && req.skip() {
+		return Response{
+			RequestID: req.RequestID,
+			Error:     errSkipped,
+		}
+	}
+	if w.executor != nil {
+		return w.executor(req.Context, req.Request)
+	}
+	return w.workDoCmd(req.Context, req.Request)
+}
+
 func (w *worker) workDoCmd(ctx context.Context, req *Request) Response {
-	var rt Response
-	if len(req.Cmd) == 1 {
-		rt = w.workDoSingle(ctx, req.Cmd[0])
-	} else {
-		rt = w.workDoGroup(ctx, req.Cmd, req.PipeMapping)
+	rt := w.workDoCmdOnce(ctx, req)
+	// 内部错误不是由用户程序导致的，丢弃本次结果并在新的环境中重新执行
+	for retry := 1; retry <= w.maxRetry && internalError(rt) && ctx.Err() == nil; retry++ {
+		w.discardResults(rt.Results)
+		rt = w.workDoCmdOnce(ctx, req)
+		for i := range rt.Results {
+			rt.Results[i].Retry = retry
+		}
 	}
 	rt.RequestID = req.RequestID
 	if w.execObserver != nil {
@@ -169,7 +325,120 @@ func (w *worker) workDoCmd(ctx context.Context, req *Request) Response {
 	return rt
 }
 
+func (w *worker) workDoCmdOnce(ctx context.Context, req *Request) Response {
+	var rt Response
+	switch {
+	case req.ShareEnv && (req.Interactive != nil || len(req.PipeMapping) > 0):
+		rt.Error = fmt.Errorf("shared environment cannot be used with pipe mapping or interactive")
+	case req.ShareEnv:
+		rt = w.workDoShared(ctx, req.Cmd)
+	case req.Interactive != nil:
+		rt = w.workDoInteractive(ctx, req.Cmd, req.Interactive)
+	case len(req.Cmd) == 1:
+		rt = w.workDoSingle(ctx, req.Cmd[0])
+	default:
+		rt = w.workDoGroup(ctx, req.Cmd, req.PipeMapping, nil)
+	}
+	return rt
+}
+
+// recoverPanic 将执行中的 panic 转换为响应中的错误，避免 worker goroutine 退出而减少并行数
+func recoverPanic(requestID string, rt *Response) {
+	if err := recover(); err != nil {
+		*rt = Response{
+			RequestID: requestID,
+			Error:     fmt.Errorf("panic during execution: %v", err),
+		}
+	}
+}
+
+// requestMemory 计算请求执行时占用的内存。同时运行的命令的内存相加，
+// 共享环境中依次执行的命令以及执行后运行的评测程序取最大值
+func (w *worker) requestMemory(req *Request) Size {
+	var total Size
+	for _, c := range req.Cmd {
+		m := c.MemoryLimit + w.extraMemoryLimit
+		if c.Checker != nil {
+			m = max(m, c.Checker.Cmd.MemoryLimit+w.extraMemoryLimit)
+		}
+		if req.ShareEnv {
+			total = max(total, m)
+		} else {
+			total += m
+		}
+	}
+	return total
+}
+
+// internalError 返回结果中是否存在不是由用户程序导致的内部错误
+func internalError(rt Response) bool {
+	for _, r := range rt.Results {
+		if envFailed(r.Status, r.Error) {
+			return true
+		}
+	}
+	return false
+}
+
+// envFailed 返回执行环境是否出现内部错误。执行程序失败 (例如文件不存在)
+// 同样被报告为内部错误，但是由用户导致，重试没有意义
+func envFailed(status envexec.Status, msg string) bool {
+	return status == envexec.StatusInternalError && !userExecError(msg)
+}
+
+func userExecError(msg string) bool {
+	for _, e := range execveInternalErrors {
+		if strings.HasPrefix(msg, e) {
+			return false
+		}
+	}
+	for _, p := range execveUserErrorPrefixes {
+		if strings.HasPrefix(msg, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// discardResults 删除重试前的结果中收集的文件以及保存到文件存储中的文件
+func (w *worker) discardResults(results []Result) {
+	for _, r := range results {
+		removeFiles(r.Files)
+		for _, id := range r.FileIDs {
+			w.fs.Remove(id)
+		}
+	}
+}
+
+// removeFiles 关闭并删除收集的文件
+func removeFiles(files map[string]*os.File) {
+	for _, f := range files {
+		f.Close()
+		os.Remove(f.Name())
+	}
+}
+
+// releaseEnv 归还执行环境，出现内部错误的环境可能已经损坏，直接销毁而不放回环境池
+func (w *worker) releaseEnv(env envexec.Environment, broken bool) {
+	if broken {
+		w.envPool.Destroy(env)
+		return
+	}
+	w.envPool.Put(env)
+}
+
 func (w *worker) workDoSingle(ctx context.Context, rc Cmd) (rt Response) {
+	var cacheKey string
+	if rc.Cache && w.cache != nil {
+		if key, ok := w.cacheKey(rc); ok {
+			if res, ok := w.cache.get(key); ok {
+				rt.Results = []Result{res}
+				return
+			}
+			cacheKey = key
+		}
+	}
+
 	c, err := w.prepareCmd(rc, make(map[string]bool))
 	if err != nil {
 		rt.Error = err
@@ -183,7 +452,8 @@ func (w *worker) workDoSingle(ctx context.Context, rc Cmd) (rt Response) {
 			Error:  fmt.Sprintf("failed to get environment %v", err),
 		}}}
 	}
-	defer w.envPool.Put(env)
+	broken := false
+	defer func() { w.releaseEnv(env, broken) }()
 	c.Environment = env
 
 	s := &envexec.Single{
@@ -192,16 +462,79 @@ func (w *worker) workDoSingle(ctx context.Context, rc Cmd) (rt Response) {
 	}
 
 	result, err := s.Run(ctx)
+	broken = envFailed(result.Status, result.Error)
+	// 内部错误的结果会被丢弃并重试，已经收集的文件不会再被使用
+	if result.Status == envexec.StatusInternalError || err != nil {
+		removeFiles(result.Files)
+	}
+	if result.Status == envexec.StatusInternalError {
+		rt.Results = []Result{{Status: result.Status, Error: result.Error}}
+		return
+	}
 	if err != nil {
 		rt.Error = err
 		return
 	}
-	res := w.convertResult(result, rc)
+	res := w.convertResult(ctx, result, rc)
+	if cacheKey != "" && res.Status == envexec.StatusAccepted {
+		w.cache.put(cacheKey, res)
+	}
 	rt.Results = []Result{res}
 	return
 }
 
-func (w *worker) workDoGroup(ctx context.Context, rc []Cmd, pm []PipeMap) (rt Response) {
+// workDoShared 在同一个环境中依次执行命令，之前命令写入的文件对之后的命令可见。
+// 命令失败后不再执行后续命令，结果只包含已执行的命令
+func (w *worker) workDoShared(ctx context.Context, rc []Cmd) (rt Response) {
+	cs := make([]*envexec.Cmd, 0, len(rc))
+	for _, cc := range rc {
+		c, err := w.prepareCmd(cc, make(map[string]bool))
+		if err != nil {
+			rt.Error = err
+			return
+		}
+		cs = append(cs, c)
+	}
+	env, err := w.envPool.Get()
+	if err != nil {
+		return Response{Results: []Result{{
+			Status: envexec.StatusInternalError,
+			Error:  fmt.Sprintf("failed to get environment %v", err),
+		}}}
+	}
+	broken := false
+	defer func() { w.releaseEnv(env, broken) }()
+
+	rt.Results = make([]Result, 0, len(cs))
+	for i, c := range cs {
+		c.Environment = env
+		s := &envexec.Single{
+			Cmd:          c,
+			NewStoreFile: w.fs.New,
+		}
+		result, err := s.Run(ctx)
+		if result.Status == envexec.StatusInternalError || err != nil {
+			removeFiles(result.Files)
+		}
+		if result.Status == envexec.StatusInternalError {
+			broken = envFailed(result.Status, result.Error)
+			rt.Results = append(rt.Results, Result{Status: result.Status, Error: result.Error})
+			return
+		}
+		if err != nil {
+			rt.Error = err
+			return
+		}
+		res := w.convertResult(ctx, result, rc[i])
+		rt.Results = append(rt.Results, res)
+		if res.Status != envexec.StatusAccepted {
+			return
+		}
+	}
+	return
+}
+
+func (w *worker) workDoGroup(ctx context.Context, rc []Cmd, pm []PipeMap, exitKill []time.Duration) (rt Response) {
 	var rts []Result
 	cs := make([]*envexec.Cmd, 0, len(rc))
 	pipeFileNames := preparePipeNames(pm, len(rc))
@@ -213,6 +546,14 @@ func (w *worker) workDoGroup(ctx context.Context, rc []Cmd, pm []PipeMap) (rt Re
 		}
 		cs = append(cs, c)
 	}
+	broken := make([]bool, len(cs))
+	defer func() {
+		for i, c := range cs {
+			if c.Environment != nil {
+				w.releaseEnv(c.Environment, broken[i])
+			}
+		}
+	}()
 	for i := range cs {
 		env, err := w.envPool.Get()
 		if err != nil {
@@ -225,22 +566,23 @@ func (w *worker) workDoGroup(ctx context.Context, rc []Cmd, pm []PipeMap) (rt Re
 			}
 			return Response{Results: res}
 		}
-		defer w.envPool.Put(env)
 		cs[i].Environment = env
 	}
 	g := envexec.Group{
 		Cmd:          cs,
 		Pipes:        pm,
 		NewStoreFile: w.fs.New,
+		ExitKill:     exitKill,
 	}
 	results, err := g.Run(ctx)
 	if err != nil {
 		rt.Error = err
 		return
 	}
-	rts = make([]Result, len(results))
+	rts = make([]Result, 0, len(results))
 	for i, result := range results {
-		res := w.convertResult(result, rc[i])
+		broken[i] = envFailed(result.Status, result.Error)
+		res := w.convertResult(ctx, result, rc[i])
 		rts = append(rts, res)
 	}
 	rt.Results = rts
@@ -359,7 +701,7 @@ func (w *worker) prepareCmdFiles(files []CmdFile, pipeFileName map[string]bool)
 	return rt, nil
 }
 
-func (w *worker) convertResult(result envexec.Result, cmd Cmd) (res Result) {
+func (w *worker) convertResult(ctx context.Context, result envexec.Result, cmd Cmd) (res Result) {
 	res.Status = result.Status
 	res.ExitStatus = result.ExitStatus
 	res.Error = result.Error
@@ -375,6 +717,15 @@ func (w *worker) convertResult(result envexec.Result, cmd Cmd) (res Result) {
 		res.Status = envexec.StatusSignalled
 	}
 
+	if res.Status == envexec.StatusAccepted {
+		switch {
+		case cmd.Checker != nil:
+			w.checkResult(ctx, &res, result, cmd.Checker)
+		case cmd.Compare != nil:
+			w.compareResult(&res, result, cmd.Compare)
+		}
+	}
+
 	copyOutCachedSet := make(map[string]bool, len(cmd.CopyOutCached))
 	for _, f := range cmd.CopyOutCached {
 		copyOutCachedSet[f.Name] = true
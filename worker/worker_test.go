@@ -0,0 +1,177 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"github.com/criyle/go-sandbox/runner"
+	"github.com/lxhcaicai/loj-judge/envexec"
+	"github.com/lxhcaicai/loj-judge/filestore"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeEnv 在 err 非空时模拟损坏的容器，failed 时模拟运行程序出现内部错误，否则模拟正常退出的程序
+type fakeEnv struct {
+	workDir *os.File
+	err     error
+	failed  bool
+}
+
+func (e *fakeEnv) Execve(context.Context, envexec.ExecveParam) (envexec.Process, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	done := make(chan struct{})
+	close(done)
+	return &fakeProcess{done: done, failed: e.failed}, nil
+}
+
+func (e *fakeEnv) WorkDir() *os.File { return e.workDir }
+
+func (e *fakeEnv) Open(string, int, os.FileMode) (*os.File, error) {
+	return nil, errors.New("not supported")
+}
+
+func (e *fakeEnv) MkdirAll(string, os.FileMode) error { return nil }
+
+func (e *fakeEnv) Symlink(string, string) error { return nil }
+
+type fakeProcess struct {
+	done   chan struct{}
+	failed bool
+}
+
+func (p *fakeProcess) Done() <-chan struct{} { return p.done }
+
+func (p *fakeProcess) Result() envexec.RunnerResult {
+	if p.failed {
+		return runner.Result{Status: runner.StatusRunnerError, Error: "runner failed"}
+	}
+	return runner.Result{Status: runner.StatusNormal}
+}
+
+func (p *fakeProcess) Usage() envexec.Usage { return envexec.Usage{} }
+
+// fakePool 依次返回 envs 中的环境并记录归还以及销毁的环境
+type fakePool struct {
+	mu        sync.Mutex
+	envs      []*fakeEnv
+	put       []*fakeEnv
+	destroyed []*fakeEnv
+}
+
+func (p *fakePool) Get() (envexec.Environment, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.envs) == 0 {
+		return nil, errors.New("no environment")
+	}
+	e := p.envs[0]
+	p.envs = p.envs[1:]
+	return e, nil
+}
+
+func (p *fakePool) Put(e envexec.Environment) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.put = append(p.put, e.(*fakeEnv))
+}
+
+func (p *fakePool) Destroy(e envexec.Environment) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.destroyed = append(p.destroyed, e.(*fakeEnv))
+}
+
+func newTestWorker(t *testing.T, envErrs ...error) (*worker, *fakePool) {
+	t.Helper()
+	dir := t.TempDir()
+	wd, err := os.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { wd.Close() })
+
+	pool := &fakePool{}
+	for _, err := range envErrs {
+		pool.envs = append(pool.envs, &fakeEnv{workDir: wd, err: err})
+	}
+	w := New(Config{
+		FileStore:             filestore.NewFileLocalStore(t.TempDir()),
+		EnvironmentPool:       pool,
+		Parallelism:           1,
+		TimeLimitTickInterval: time.Millisecond,
+		MaxRetry:              2,
+	}).(*worker)
+	return w, pool
+}
+
+func TestRetryBrokenEnv(t *testing.T) {
+	// 与容器通信失败同样以 execve: 开头，但是环境已经损坏
+	broken := errors.New("execve: recvReply read unix: connection reset by peer")
+	w, pool := newTestWorker(t, broken, nil)
+
+	rt := w.workDoCmd(context.Background(), &Request{Cmd: []Cmd{{Args: []string{"a.out"}}}})
+	if rt.Error != nil || len(rt.Results) != 1 {
+		t.Fatalf("unexpected response: %+v", rt)
+	}
+	if r := rt.Results[0]; r.Status != envexec.StatusAccepted || r.Retry != 1 {
+		t.Fatalf("expected accepted after one retry, got %v retry %d: %s", r.Status, r.Retry, r.Error)
+	}
+	if len(pool.destroyed) != 1 || pool.destroyed[0].err != broken {
+		t.Fatalf("expected the broken environment destroyed, got %+v", pool.destroyed)
+	}
+	if len(pool.put) != 1 || pool.put[0].err != nil {
+		t.Fatalf("expected the healthy environment put back, got %+v", pool.put)
+	}
+}
+
+func TestNoRetryUserExecError(t *testing.T) {
+	for _, msg := range []string{
+		"execve: execve: start: fork/exec /w/a.out: exec format error",
+		"execve: handle: a.out: executable file not found in $PATH",
+	} {
+		w, pool := newTestWorker(t, errors.New(msg), nil)
+
+		rt := w.workDoCmd(context.Background(), &Request{Cmd: []Cmd{{Args: []string{"a.out"}}}})
+		if len(rt.Results) != 1 {
+			t.Fatalf("unexpected response: %+v", rt)
+		}
+		if r := rt.Results[0]; r.Status != envexec.StatusInternalError || r.Retry != 0 || r.Error != msg {
+			t.Fatalf("%s: expected internal error without retry, got %v retry %d: %s", msg, r.Status, r.Retry, r.Error)
+		}
+		if len(pool.destroyed) != 0 || len(pool.put) != 1 {
+			t.Fatalf("%s: expected the environment put back, destroyed %d put %d", msg, len(pool.destroyed), len(pool.put))
+		}
+	}
+}
+
+func TestRetryRemovesCollectedFiles(t *testing.T) {
+	w, pool := newTestWorker(t, nil, nil)
+	pool.envs[0].failed = true
+	dir := t.TempDir()
+	w.fs = filestore.NewFileLocalStore(dir)
+
+	rt := w.workDoCmd(context.Background(), &Request{Cmd: []Cmd{{
+		Args:  []string{"a.out"},
+		Files: []CmdFile{&ReaderFile{Reader: strings.NewReader("")}, &Collector{Name: "stdout", Max: 1024, Pipe: true}},
+	}}})
+	if rt.Error != nil || len(rt.Results) != 1 {
+		t.Fatalf("unexpected response: %+v", rt)
+	}
+	if r := rt.Results[0]; r.Status != envexec.StatusAccepted || r.Retry != 1 {
+		t.Fatalf("expected accepted after one retry, got %v retry %d: %s", r.Status, r.Retry, r.Error)
+	}
+	// 丢弃最后一次执行的结果之后，重试之前收集的文件也不应该留下
+	w.discardResults(rt.Results)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected files collected before retry removed, got %d files", len(entries))
+	}
+}